@@ -0,0 +1,209 @@
+package mezvaro
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// accessLogEntry is data about request available to access log directives.
+type accessLogEntry struct {
+	c        *Context
+	start    time.Time
+	duration time.Duration
+	status   int
+	bytes    int64
+}
+
+// accessLogPart writes part of access log line.
+type accessLogPart func(b *strings.Builder, e *accessLogEntry)
+
+// accessLogDirectives are supported access log directives without
+// parameter.
+var accessLogDirectives = map[byte]accessLogPart{
+	'%': func(b *strings.Builder, e *accessLogEntry) { b.WriteByte('%') },
+	'h': func(b *strings.Builder, e *accessLogEntry) {
+		host, _, err := net.SplitHostPort(e.c.Request.RemoteAddr)
+		if err != nil {
+			host = e.c.Request.RemoteAddr
+		}
+		writeOrDash(b, host)
+	},
+	'l': func(b *strings.Builder, e *accessLogEntry) { b.WriteByte('-') },
+	'u': func(b *strings.Builder, e *accessLogEntry) {
+		user, _, _ := e.c.Request.BasicAuth()
+		writeOrDash(b, user)
+	},
+	't': func(b *strings.Builder, e *accessLogEntry) {
+		b.WriteString(e.start.Format("[02/Jan/2006:15:04:05 -0700]"))
+	},
+	'r': func(b *strings.Builder, e *accessLogEntry) {
+		b.WriteString(e.c.Request.Method + " " + e.c.RequestURI() + " " + e.c.Request.Proto)
+	},
+	'm': func(b *strings.Builder, e *accessLogEntry) { b.WriteString(e.c.Request.Method) },
+	'U': func(b *strings.Builder, e *accessLogEntry) { b.WriteString(e.c.Request.URL.Path) },
+	'q': func(b *strings.Builder, e *accessLogEntry) {
+		if e.c.Request.URL.RawQuery != "" {
+			b.WriteString("?" + e.c.Request.URL.RawQuery)
+		}
+	},
+	'H': func(b *strings.Builder, e *accessLogEntry) { b.WriteString(e.c.Request.Proto) },
+	's': func(b *strings.Builder, e *accessLogEntry) { b.WriteString(strconv.Itoa(e.status)) },
+	'b': func(b *strings.Builder, e *accessLogEntry) {
+		if e.bytes == 0 {
+			b.WriteByte('-')
+			return
+		}
+		b.WriteString(strconv.FormatInt(e.bytes, 10))
+	},
+	'B': func(b *strings.Builder, e *accessLogEntry) { b.WriteString(strconv.FormatInt(e.bytes, 10)) },
+	'D': func(b *strings.Builder, e *accessLogEntry) {
+		b.WriteString(strconv.FormatInt(e.duration.Microseconds(), 10))
+	},
+	'T': func(b *strings.Builder, e *accessLogEntry) {
+		b.WriteString(strconv.FormatInt(int64(e.duration/time.Second), 10))
+	},
+}
+
+// AccessLog returns middleware that writes line per request to out, in
+// provided format with Apache style directives, after rest of the chain
+// finishes. Supported directives are:
+//
+//	%h       remote host
+//	%l       remote logname, always "-"
+//	%u       user from basic authentication
+//	%t       time request was received
+//	%r       request line, like "GET /users?page=2 HTTP/1.1"
+//	%m       method
+//	%U       URL path
+//	%q       query string, prefixed with "?" if not empty
+//	%H       protocol
+//	%s       status
+//	%b       size of response body in bytes, "-" if there is no body
+//	%B       size of response body in bytes
+//	%D       duration in microseconds
+//	%T       duration in seconds
+//	%{Name}i value of request header
+//	%{Name}o value of response header
+//	%%       literal "%"
+//
+// Empty values are written as "-". For example, Common Log Format is
+// `%h %l %u %t "%r" %s %b`. It panics if format contains unknown directive,
+// so invalid format fails when chain is built.
+func AccessLog(format string, out io.Writer) Handler {
+	parts := compileAccessLog(format)
+	var mu sync.Mutex
+	return HandlerFunc(func(c *Context) {
+		e := &accessLogEntry{c: c, start: time.Now()}
+		original := c.Response
+		c.SetResponse(&countingWriter{ResponseWriter: original, count: &e.bytes})
+		defer func() {
+			c.Response = original
+		}()
+		c.Next()
+
+		e.duration = time.Since(e.start)
+		e.status = c.responseStatus()
+		var b strings.Builder
+		for _, part := range parts {
+			part(&b, e)
+		}
+		b.WriteByte('\n')
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(out, b.String())
+	})
+}
+
+// compileAccessLog parses access log format into parts that write it.
+func compileAccessLog(format string) []accessLogPart {
+	var parts []accessLogPart
+	literal := func(s string) {
+		if s != "" {
+			parts = append(parts, func(b *strings.Builder, e *accessLogEntry) { b.WriteString(s) })
+		}
+	}
+	for {
+		i := strings.IndexByte(format, '%')
+		if i < 0 {
+			literal(format)
+			return parts
+		}
+		literal(format[:i])
+		format = format[i+1:]
+		if format == "" {
+			panic("mezvaro: access log format ends with %")
+		}
+		if format[0] == '{' {
+			end := strings.IndexByte(format, '}')
+			if end < 0 || end+1 >= len(format) {
+				panic("mezvaro: unterminated access log directive %" + format)
+			}
+			name := format[1:end]
+			switch format[end+1] {
+			case 'i':
+				parts = append(parts, func(b *strings.Builder, e *accessLogEntry) {
+					writeOrDash(b, e.c.Request.Header.Get(name))
+				})
+			case 'o':
+				parts = append(parts, func(b *strings.Builder, e *accessLogEntry) {
+					writeOrDash(b, e.c.Response.Header().Get(name))
+				})
+			default:
+				panic(fmt.Sprintf("mezvaro: unknown access log directive %%{%s}%c", name, format[end+1]))
+			}
+			format = format[end+2:]
+			continue
+		}
+		part, ok := accessLogDirectives[format[0]]
+		if !ok {
+			panic(fmt.Sprintf("mezvaro: unknown access log directive %%%c", format[0]))
+		}
+		parts = append(parts, part)
+		format = format[1:]
+	}
+}
+
+// writeOrDash writes value, or "-" if value is empty.
+func writeOrDash(b *strings.Builder, value string) {
+	if value == "" {
+		value = "-"
+	}
+	b.WriteString(value)
+}
+
+// countingWriter counts bytes of response body written through it.
+type countingWriter struct {
+	http.ResponseWriter
+	count *int64
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *countingWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	*w.count += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher interface, if underlying writer supports it.
+func (w *countingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports it.
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns underlying response writer.
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
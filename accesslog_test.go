@@ -0,0 +1,52 @@
+package mezvaro
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	var out bytes.Buffer
+	m := New(AccessLog(`%h %l %u %t "%r" %s %b %D "%{User-Agent}i" %{Content-Type}o %{X-Missing}i 100%%`, &out))
+	m.UseFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		c.Response.WriteHeader(http.StatusCreated)
+		c.Response.Write([]byte("created"))
+	})
+	request := httptest.NewRequest("POST", "/users?page=2", nil)
+	request.RemoteAddr = "192.0.2.1:1234"
+	request.SetBasicAuth("alice", "secret")
+	request.Header.Set("User-Agent", "curl/8.0")
+	m.ServeHTTP(httptest.NewRecorder(), request)
+
+	expected := regexp.MustCompile(`^192\.0\.2\.1 - alice \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"POST /users\?page=2 HTTP/1\.1" 201 7 \d+ "curl/8\.0" text/plain - 100%\n$`)
+	if !expected.MatchString(out.String()) {
+		t.Fatal("Wrong access log line: ", out.String())
+	}
+}
+
+func TestAccessLogEmptyResponse(t *testing.T) {
+	var out bytes.Buffer
+	m := New(AccessLog("%m %U%q %H %s %b %B", &out))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	if out.String() != "GET /health HTTP/1.1 200 - 0\n" {
+		t.Fatal("Wrong access log line: ", out.String())
+	}
+}
+
+func TestAccessLogInvalidFormat(t *testing.T) {
+	for _, format := range []string{"%x", "%", "%{Host", "%{Host}z"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Invalid format accepted: ", format)
+				}
+			}()
+			AccessLog(format, &bytes.Buffer{})
+		}()
+	}
+}
@@ -0,0 +1,20 @@
+package mezvaro
+
+import (
+	"net/http"
+	"strings"
+)
+
+// realmEscaper escapes characters that are not allowed unescaped in quoted
+// string of authentication challenge.
+var realmEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// ChallengeBasicAuth responds with 401 Unauthorized status and
+// WWW-Authenticate header that asks client for credentials for basic
+// authentication in provided realm, and aborts chain. It is intended for
+// handlers that implement their own authentication flow.
+func (c *Context) ChallengeBasicAuth(realm string) {
+	c.Response.Header().Set("WWW-Authenticate", `Basic realm="`+realmEscaper.Replace(realm)+`"`)
+	http.Error(c.Response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	c.Abort()
+}
@@ -0,0 +1,23 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestChallengeBasicAuth(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := newContext(response, nil, nil, nil)
+	c.ChallengeBasicAuth(`Admin "area" \ private`)
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected 401, got: ", response.Code)
+	}
+	expected := `Basic realm="Admin \"area\" \\ private"`
+	if header := response.Header().Get("WWW-Authenticate"); header != expected {
+		t.Fatal("Wrong challenge: ", header)
+	}
+	if !c.IsAborted() {
+		t.Fatal("Chain not aborted.")
+	}
+}
@@ -0,0 +1,117 @@
+package mezvaro
+
+import (
+	"sync"
+	"time"
+)
+
+// Thresholds of batches collected with Batch method.
+var (
+	// BatchSize is number of items after which batch is flushed.
+	BatchSize = 100
+	// BatchDelay is maximal time items wait in batch before it is flushed.
+	BatchDelay = 50 * time.Millisecond
+)
+
+// batch is collection of items waiting to be flushed.
+type batch struct {
+	mu    sync.Mutex
+	items []interface{}
+	flush func([]interface{})
+	timer *time.Timer
+	// inflight tracks flushes in progress, so FlushBatches can wait for
+	// them.
+	inflight sync.WaitGroup
+}
+
+// Batch adds item to batch with provided key, for endpoints that aggregate
+// many writes into fewer bulk operations. Batch is passed to flush when it has
+// BatchSize items, or when BatchDelay passes since first item was added,
+// whichever comes first. Delay is shortened to deadline of context, so items
+// are flushed before request times out. Batches are scoped to request, and
+// flush function passed with the last item is used. Flush triggered by size
+// is called synchronously, while flush triggered by time is called from
+// another goroutine. Items still waiting when chain finishes are flushed
+// before response is finished, or earlier with FlushBatches.
+func (c *Context) Batch(key string, item interface{}, flush func([]interface{})) {
+	c.mu.Lock()
+	if c.batches == nil {
+		c.batches = make(map[string]*batch)
+	}
+	b, ok := c.batches[key]
+	if !ok {
+		b = &batch{}
+		c.batches[key] = b
+	}
+	deadline, hasDeadline := c.netCtx.Deadline()
+	c.mu.Unlock()
+
+	b.mu.Lock()
+	b.items = append(b.items, item)
+	b.flush = flush
+	if len(b.items) >= BatchSize {
+		items := b.take()
+		b.inflight.Add(1)
+		b.mu.Unlock()
+		defer b.inflight.Done()
+		flush(items)
+		return
+	}
+	if b.timer == nil {
+		delay := BatchDelay
+		if hasDeadline && time.Until(deadline) < delay {
+			delay = time.Until(deadline)
+		}
+		if delay < 0 {
+			// deadline has already passed
+			delay = 0
+		}
+		b.timer = time.AfterFunc(delay, b.flushPending)
+	}
+	b.mu.Unlock()
+}
+
+// FlushBatches flushes items waiting in all batches of request (see Batch)
+// and waits until all flushes in progress are finished, including those
+// triggered by time. Handlers can call it before writing response, to report
+// whether items have been written. It is called automatically when chain
+// finishes.
+func (c *Context) FlushBatches() {
+	c.mu.Lock()
+	batches := make([]*batch, 0, len(c.batches))
+	for _, b := range c.batches {
+		batches = append(batches, b)
+	}
+	c.mu.Unlock()
+	for _, b := range batches {
+		b.flushPending()
+		b.inflight.Wait()
+	}
+}
+
+// take removes and returns items of batch and stops its timer. Caller has to
+// hold batch lock.
+func (b *batch) take() []interface{} {
+	items := b.items
+	b.items = nil
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	return items
+}
+
+// flushPending flushes items waiting in batch, if there are any.
+func (b *batch) flushPending() {
+	b.mu.Lock()
+	items := b.take()
+	flush := b.flush
+	if len(items) == 0 {
+		b.mu.Unlock()
+		return
+	}
+	b.inflight.Add(1)
+	b.mu.Unlock()
+	defer b.inflight.Done()
+	flush(items)
+}
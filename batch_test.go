@@ -0,0 +1,91 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBatchFlushOnSize(t *testing.T) {
+	previous := BatchSize
+	BatchSize = 3
+	defer func() { BatchSize = previous }()
+	c := NewContext(httptest.NewRecorder(), nil)
+	var flushed [][]interface{}
+	flush := func(items []interface{}) { flushed = append(flushed, items) }
+	for i := 0; i < 4; i++ {
+		c.Batch("writes", i, flush)
+	}
+	if len(flushed) != 1 || len(flushed[0]) != 3 || flushed[0][0] != 0 || flushed[0][2] != 2 {
+		t.Fatal("Batch not flushed on size: ", flushed)
+	}
+}
+
+func TestBatchFlushOnDeadline(t *testing.T) {
+	previous := BatchDelay
+	BatchDelay = time.Hour
+	defer func() { BatchDelay = previous }()
+	c := NewContext(httptest.NewRecorder(), nil)
+	cancel := c.WithTimeout(20 * time.Millisecond)
+	defer cancel()
+	flushed := make(chan []interface{}, 2)
+	flush := func(items []interface{}) { flushed <- items }
+	c.Batch("writes", "first", flush)
+	c.Batch("writes", "second", flush)
+	c.Batch("other", "third", flush)
+	for i := 0; i < 2; i++ {
+		select {
+		case items := <-flushed:
+			if len(items) != 2 && (len(items) != 1 || items[0] != "third") {
+				t.Fatal("Wrong items flushed: ", items)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("Batch not flushed before deadline.")
+		}
+	}
+}
+
+func TestFlushBatches(t *testing.T) {
+	previous := BatchDelay
+	BatchDelay = time.Hour
+	defer func() { BatchDelay = previous }()
+	c := NewContext(httptest.NewRecorder(), nil)
+	var flushed []interface{}
+	c.Batch("writes", "first", func(items []interface{}) { flushed = append(flushed, items...) })
+	c.Batch("writes", "second", func(items []interface{}) { flushed = append(flushed, items...) })
+	c.FlushBatches()
+	if len(flushed) != 2 {
+		t.Fatal("Pending items not flushed: ", flushed)
+	}
+	c.FlushBatches()
+	if len(flushed) != 2 {
+		t.Fatal("Items flushed twice: ", flushed)
+	}
+}
+
+func TestBatchFlushedWhenChainFinishes(t *testing.T) {
+	previous := BatchDelay
+	BatchDelay = time.Hour
+	defer func() { BatchDelay = previous }()
+	var flushed []interface{}
+	m := New(HandlerFunc(func(c *Context) {
+		c.Batch("writes", "item", func(items []interface{}) { flushed = items })
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/", nil))
+	if len(flushed) != 1 {
+		t.Fatal("Pending items not flushed after chain: ", flushed)
+	}
+}
+
+func TestBatchDeadlinePassed(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	cancel := c.WithDeadline(time.Now().Add(-time.Second))
+	defer cancel()
+	flushed := make(chan []interface{}, 1)
+	c.Batch("writes", "late", func(items []interface{}) { flushed <- items })
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Batch not flushed after deadline passed.")
+	}
+}
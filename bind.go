@@ -0,0 +1,375 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BindError is returned when request data can not be bound to target value.
+// It carries details about failure that can be used for building response.
+type BindError struct {
+	// Format of data that was bound, one of "json", "uri", "query" and
+	// "multipart", or "charset" if body could not be transcoded.
+	Format string
+	// Offset in input after which error occurred. It is set only for
+	// errors in JSON syntax and JSON type mismatches, otherwise it is 0.
+	Offset int64
+	// Message is description of error that is safe to show to users.
+	Message string
+	// Err is underlying error.
+	Err error
+}
+
+// Error implements error interface.
+func (e *BindError) Error() string {
+	return "mezvaro: binding " + e.Format + " failed: " + e.Message
+}
+
+// Unwrap returns underlying error.
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// newJSONBindError wraps error returned by JSON decoder in BindError.
+func newJSONBindError(err error) *BindError {
+	bindErr := &BindError{Format: "json", Message: "invalid JSON", Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		bindErr.Offset = syntaxErr.Offset
+		bindErr.Message = fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		bindErr.Offset = typeErr.Offset
+		bindErr.Message = fmt.Sprintf("invalid type for field %q", typeErr.Field)
+	case errors.Is(err, io.EOF):
+		bindErr.Message = "empty body"
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		bindErr.Message = "unexpected end of JSON"
+	}
+	return bindErr
+}
+
+// BindJSON decodes JSON body of request into v. If body is not valid JSON
+// or does not match v, returned error is *BindError.
+func (c *Context) BindJSON(v interface{}) error {
+	return c.bindJSON(v, false)
+}
+
+// BindJSONUseNumber decodes JSON body of request into v like BindJSON, but
+// numbers decoded into interface{} values are represented as json.Number
+// instead of float64. This prevents loss of precision for large integers,
+// like big IDs.
+func (c *Context) BindJSONUseNumber(v interface{}) error {
+	return c.bindJSON(v, true)
+}
+
+// bindJSON decodes JSON body of request into v.
+func (c *Context) bindJSON(v interface{}, useNumber bool) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("mezvaro: request has no body")
+	}
+	body, err := c.bodyReader()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(body)
+	if useNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return newJSONBindError(err)
+	}
+	return nil
+}
+
+// DecodeStream passes decoder of JSON body of request to fn, for streaming
+// processing of large bodies, like big JSON arrays, element by element instead
+// of loading whole body into memory. Body is read the same way as in BindJSON,
+// respecting charset transcoding and limits of body size (for example set
+// with http.MaxBytesReader). Reading of body fails with context error once
+// context is cancelled or its deadline expires, so decoding stops. Error
+// returned by fn is returned.
+func (c *Context) DecodeStream(fn func(*json.Decoder) error) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("mezvaro: request has no body")
+	}
+	body, err := c.bodyReader()
+	if err != nil {
+		return err
+	}
+	return fn(json.NewDecoder(&contextReader{c: c, r: body}))
+}
+
+// contextReader reads from underlying reader until context is done.
+type contextReader struct {
+	c *Context
+	r io.Reader
+}
+
+// Read implements io.Reader interface.
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.c.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// BindWithDefaults decodes JSON body of request into v like BindJSON does, and
+// afterwards sets fields of struct pointed by v that still have zero value to
+// defaults provided in "default" struct tag. For slice fields, default is comma
+// separated list of values. Empty body is not considered an error, in that
+// case only defaults are applied.
+func (c *Context) BindWithDefaults(v interface{}) error {
+	if c.Request != nil && c.Request.Body != nil {
+		var bindErr *BindError
+		if err := c.BindJSON(v); err != nil && !(errors.As(err, &bindErr) && bindErr.Err == io.EOF) {
+			return err
+		}
+	}
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return errors.New("mezvaro: bind target has to be non-nil pointer to struct")
+	}
+	return applyDefaults(ptr.Elem())
+}
+
+// applyDefaults sets zero value fields of struct to values from "default"
+// struct tag. Nested structs are processed recursively.
+func applyDefaults(target reflect.Value) error {
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value := target.Field(i)
+		if value.Kind() == reflect.Struct {
+			if err := applyDefaults(value); err != nil {
+				return err
+			}
+			continue
+		}
+		def, ok := field.Tag.Lookup("default")
+		if !ok || !value.IsZero() {
+			continue
+		}
+		values := []string{def}
+		if value.Kind() == reflect.Slice {
+			values = strings.Split(def, ",")
+		}
+		if err := setField(value, values); err != nil {
+			return fmt.Errorf("mezvaro: invalid default for field %s: %v", field.Name, err)
+		}
+	}
+	return nil
+}
+
+// BindURI binds URL parameters to fields of struct pointed by v. Fields are
+// matched by name provided in "uri" struct tag or by field name if tag is not
+// present. Fields with tag "-" are skipped. If value of parameter can not be
+// converted to type of field, returned error is *BindError.
+func (c *Context) BindURI(v interface{}) error {
+	params := make(map[string][]string, len(c.urlParams))
+	for name, value := range c.urlParams {
+		params[name] = []string{value}
+	}
+	return bindValues(v, params, "uri", "uri")
+}
+
+// BindQuery binds URL query parameters to fields of struct pointed by v.
+// Fields are matched by name provided in "form" struct tag or by field name
+// if tag is not present. Fields with tag "-" are skipped. Slice fields
+// receive all values of repeated parameter. If value of parameter can not be
+// converted to type of field, returned error is *BindError.
+func (c *Context) BindQuery(v interface{}) error {
+	return bindValues(v, c.Request.URL.Query(), "form", "query")
+}
+
+// MaxMultipartMemory is maximal number of bytes of multipart form kept in
+// memory by BindMultipart. Rest of the form, like content of large files, is
+// stored in temporary files.
+var MaxMultipartMemory int64 = 32 << 20
+
+// fileHeaderType is type of fields that receive uploaded files.
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// BindMultipart parses multipart form from body of request and binds it to
+// fields of struct pointed by v. Text fields are bound like in BindQuery,
+// matched by name in "form" struct tag. Uploaded files are bound to fields
+// of type *multipart.FileHeader, or []*multipart.FileHeader for multiple
+// files, matched by name in "file" struct tag. At most MaxMultipartMemory
+// bytes of form are kept in memory. If form can not be parsed or value of
+// text field can not be converted to type of field, returned error is
+// *BindError.
+func (c *Context) BindMultipart(v interface{}) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return errors.New("mezvaro: request has no body")
+	}
+	if err := c.Request.ParseMultipartForm(MaxMultipartMemory); err != nil {
+		return &BindError{Format: "multipart", Message: "invalid multipart form", Err: err}
+	}
+	form := c.Request.MultipartForm
+	if err := bindValues(v, form.Value, "form", "multipart"); err != nil {
+		return err
+	}
+	target := reflect.ValueOf(v).Elem()
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		name, ok := field.Tag.Lookup("file")
+		if !ok || field.PkgPath != "" {
+			continue
+		}
+		files := form.File[name]
+		if len(files) == 0 {
+			continue
+		}
+		switch field.Type {
+		case fileHeaderType:
+			target.Field(i).Set(reflect.ValueOf(files[0]))
+		case reflect.SliceOf(fileHeaderType):
+			target.Field(i).Set(reflect.ValueOf(files))
+		default:
+			return &BindError{
+				Format:  "multipart",
+				Message: fmt.Sprintf("invalid field for file %q", name),
+				Err:     fmt.Errorf("unsupported type %s", field.Type),
+			}
+		}
+	}
+	return nil
+}
+
+// MustBindURI binds URL parameters like BindURI does, but if binding fails
+// it responds with 400 Bad Request status and aborts chain. Returns boolean
+// indicating if binding succeeded.
+func (c *Context) MustBindURI(v interface{}) bool {
+	return c.mustBind(c.BindURI(v))
+}
+
+// MustBindQuery binds URL query parameters like BindQuery does, but if binding
+// fails it responds with 400 Bad Request status and aborts chain. Returns
+// boolean indicating if binding succeeded.
+func (c *Context) MustBindQuery(v interface{}) bool {
+	return c.mustBind(c.BindQuery(v))
+}
+
+// mustBind renders error and aborts chain if binding failed.
+func (c *Context) mustBind(err error) bool {
+	if err != nil {
+		c.renderError(http.StatusBadRequest, err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// bindValues sets fields of struct pointed by v from provided values, matching
+// them by name from provided struct tag. Format is used for reporting errors.
+func bindValues(v interface{}, values map[string][]string, tag, format string) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return errors.New("mezvaro: bind target has to be non-nil pointer to struct")
+	}
+	target := ptr.Elem()
+	targetType := target.Type()
+	for i := 0; i < targetType.NumField(); i++ {
+		field := targetType.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+		if _, ok := field.Tag.Lookup("file"); ok {
+			// uploaded files are bound separately
+			continue
+		}
+		name := field.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fieldValues, ok := values[name]
+		if !ok || len(fieldValues) == 0 {
+			continue
+		}
+		if err := setField(target.Field(i), fieldValues); err != nil {
+			return &BindError{
+				Format:  format,
+				Message: fmt.Sprintf("invalid value for %q", name),
+				Err:     err,
+			}
+		}
+	}
+	return nil
+}
+
+// setField sets value of field from string representations. For slices,
+// all values are used, for other types only the first one.
+func setField(field reflect.Value, values []string) error {
+	switch field.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(slice.Index(i), value); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	case reflect.Ptr:
+		value := reflect.New(field.Type().Elem())
+		if err := setField(value.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(value)
+		return nil
+	default:
+		return setValue(field, values[0])
+	}
+}
+
+// setValue parses string representation of value according to kind of field
+// and sets it.
+func setValue(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package mezvaro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+type uriParams struct {
+	ID   int    `uri:"id"`
+	Slug string `uri:"slug"`
+}
+
+type queryParams struct {
+	Page    int      `form:"page"`
+	Tags    []string `form:"tag"`
+	Active  *bool    `form:"active"`
+	Ignored string   `form:"-"`
+}
+
+func TestBindURI(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, map[string]string{
+		"id":   "42",
+		"slug": "hello",
+	})
+	var params uriParams
+	if !c.MustBindURI(&params) {
+		t.Fatal("Binding URI parameters failed.")
+	}
+	if params.ID != 42 || params.Slug != "hello" {
+		t.Fatal("URI parameters not bound correctly: ", params)
+	}
+	if c.IsAborted() {
+		t.Fatal("Context aborted after successful binding.")
+	}
+}
+
+func TestMustBindURIFailure(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := newContext(response, nil, nil, map[string]string{"id": "not-a-number"})
+	var params uriParams
+	if c.MustBindURI(&params) {
+		t.Fatal("Binding succeeded with invalid value.")
+	}
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected status 400, got: ", response.Code)
+	}
+	if !c.IsAborted() {
+		t.Fatal("Context not aborted after failed binding.")
+	}
+}
+
+func TestBindQuery(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/?page=3&tag=a&tag=b&active=true&Ignored=x", nil)
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var params queryParams
+	if !c.MustBindQuery(&params) {
+		t.Fatal("Binding query parameters failed.")
+	}
+	if params.Page != 3 {
+		t.Fatal("Wrong page: ", params.Page)
+	}
+	if len(params.Tags) != 2 || params.Tags[0] != "a" || params.Tags[1] != "b" {
+		t.Fatal("Wrong tags: ", params.Tags)
+	}
+	if params.Active == nil || !*params.Active {
+		t.Fatal("Pointer field not bound.")
+	}
+	if params.Ignored != "" {
+		t.Fatal("Ignored field bound.")
+	}
+}
+
+func TestMustBindQueryFailure(t *testing.T) {
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/?page=first", nil)
+	c := newContext(response, request, nil, nil)
+	var params queryParams
+	if c.MustBindQuery(&params) {
+		t.Fatal("Binding succeeded with invalid value.")
+	}
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected status 400, got: ", response.Code)
+	}
+	if !c.IsAborted() {
+		t.Fatal("Context not aborted after failed binding.")
+	}
+}
+
+func TestBindInvalidTarget(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	var notStruct int
+	if err := c.BindURI(&notStruct); err == nil {
+		t.Fatal("Binding to non-struct did not fail.")
+	}
+	if err := c.BindURI(uriParams{}); err == nil {
+		t.Fatal("Binding to non-pointer did not fail.")
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name": "mezvaro", "id": 42}`))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body struct {
+		Name string `json:"name"`
+		ID   int    `json:"id"`
+	}
+	if err := c.BindJSON(&body); err != nil {
+		t.Fatal("Binding JSON failed: ", err)
+	}
+	if body.Name != "mezvaro" || body.ID != 42 {
+		t.Fatal("JSON not bound correctly: ", body)
+	}
+}
+
+func TestBindJSONUseNumber(t *testing.T) {
+	const bigID = "9007199254740993"
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(`{"id": `+bigID+`}`))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body map[string]interface{}
+	if err := c.BindJSONUseNumber(&body); err != nil {
+		t.Fatal("Binding JSON failed: ", err)
+	}
+	id, ok := body["id"].(json.Number)
+	if !ok {
+		t.Fatalf("Expected json.Number, got: %T", body["id"])
+	}
+	if id.String() != bigID {
+		t.Fatal("Large integer lost precision: ", id)
+	}
+}
+
+func TestBindJSONWithoutBody(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	var body map[string]interface{}
+	if err := c.BindJSON(&body); err == nil {
+		t.Fatal("Binding without request did not fail.")
+	}
+}
+
+func TestBindJSONSyntaxError(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name": }`))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body map[string]interface{}
+	err := c.BindJSON(&body)
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatal("Expected BindError, got: ", err)
+	}
+	if bindErr.Format != "json" {
+		t.Fatal("Wrong format: ", bindErr.Format)
+	}
+	if bindErr.Offset == 0 {
+		t.Fatal("Offset of syntax error not set.")
+	}
+	var syntaxErr *json.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatal("Underlying syntax error not available.")
+	}
+}
+
+func TestBindQueryError(t *testing.T) {
+	request, _ := http.NewRequest("GET", "/?page=first", nil)
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var params queryParams
+	err := c.BindQuery(&params)
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatal("Expected BindError, got: ", err)
+	}
+	if bindErr.Format != "query" {
+		t.Fatal("Wrong format: ", bindErr.Format)
+	}
+	if bindErr.Message != `invalid value for "page"` {
+		t.Fatal("Wrong message: ", bindErr.Message)
+	}
+}
+
+type defaultsBody struct {
+	Name    string   `json:"name" default:"anonymous"`
+	Limit   int      `json:"limit" default:"10"`
+	Tags    []string `json:"tags" default:"a,b"`
+	Enabled *bool    `json:"enabled" default:"true"`
+	Nested  struct {
+		Ratio float64 `json:"ratio" default:"0.5"`
+	} `json:"nested"`
+	NoDefault string `json:"no_default"`
+}
+
+func TestBindWithDefaults(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name": "mezvaro"}`))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body defaultsBody
+	if err := c.BindWithDefaults(&body); err != nil {
+		t.Fatal("Binding failed: ", err)
+	}
+	if body.Name != "mezvaro" {
+		t.Fatal("Bound value overridden by default: ", body.Name)
+	}
+	if body.Limit != 10 {
+		t.Fatal("Default not applied to scalar field: ", body.Limit)
+	}
+	if len(body.Tags) != 2 || body.Tags[0] != "a" || body.Tags[1] != "b" {
+		t.Fatal("Default not applied to slice field: ", body.Tags)
+	}
+	if body.Enabled == nil || !*body.Enabled {
+		t.Fatal("Default not applied to pointer field.")
+	}
+	if body.Nested.Ratio != 0.5 {
+		t.Fatal("Default not applied to nested field: ", body.Nested.Ratio)
+	}
+	if body.NoDefault != "" {
+		t.Fatal("Field without default changed: ", body.NoDefault)
+	}
+}
+
+func TestBindWithDefaultsEmptyBody(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(""))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body defaultsBody
+	if err := c.BindWithDefaults(&body); err != nil {
+		t.Fatal("Binding empty body failed: ", err)
+	}
+	if body.Name != "anonymous" || body.Limit != 10 {
+		t.Fatal("Defaults not applied for empty body: ", body)
+	}
+}
+
+func TestBindWithDefaultsInvalidJSON(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader("{"))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var body defaultsBody
+	if err := c.BindWithDefaults(&body); err == nil {
+		t.Fatal("Invalid JSON accepted.")
+	}
+}
+
+type uploadForm struct {
+	Title       string                  `form:"title"`
+	Size        int                     `form:"size"`
+	Avatar      *multipart.FileHeader   `file:"avatar"`
+	Attachments []*multipart.FileHeader `file:"attachment"`
+}
+
+func newMultipartRequest(t *testing.T, fields map[string]string, files map[string][]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	for name, contents := range files {
+		for i, content := range contents {
+			part, err := writer.CreateFormFile(name, name+string(rune('0'+i))+".txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			part.Write([]byte(content))
+		}
+	}
+	writer.Close()
+	request, _ := http.NewRequest("POST", "/", body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestBindMultipart(t *testing.T) {
+	request := newMultipartRequest(t,
+		map[string]string{"title": "report", "size": "42"},
+		map[string][]string{"avatar": {"image"}, "attachment": {"first", "second"}})
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var form uploadForm
+	if err := c.BindMultipart(&form); err != nil {
+		t.Fatal("Binding multipart form failed: ", err)
+	}
+	if form.Title != "report" || form.Size != 42 {
+		t.Fatal("Wrong text fields: ", form.Title, form.Size)
+	}
+	if form.Avatar == nil {
+		t.Fatal("File field not bound.")
+	}
+	file, _ := form.Avatar.Open()
+	content, _ := io.ReadAll(file)
+	file.Close()
+	if string(content) != "image" {
+		t.Fatal("Wrong file content: ", string(content))
+	}
+	if len(form.Attachments) != 2 || form.Attachments[1].Filename != "attachment1.txt" {
+		t.Fatal("Wrong attachments: ", form.Attachments)
+	}
+}
+
+func TestBindMultipartMissingFile(t *testing.T) {
+	request := newMultipartRequest(t, map[string]string{"title": "report"}, nil)
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var form uploadForm
+	if err := c.BindMultipart(&form); err != nil {
+		t.Fatal("Binding multipart form failed: ", err)
+	}
+	if form.Avatar != nil || form.Attachments != nil {
+		t.Fatal("Missing files bound.")
+	}
+}
+
+func TestBindMultipartErrors(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", strings.NewReader("title=report"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	var form uploadForm
+	var bindErr *BindError
+	if err := c.BindMultipart(&form); !errors.As(err, &bindErr) || bindErr.Format != "multipart" {
+		t.Fatal("Expected multipart bind error, got: ", err)
+	}
+
+	request = newMultipartRequest(t, map[string]string{"size": "big"}, nil)
+	c = newContext(httptest.NewRecorder(), request, nil, nil)
+	if err := c.BindMultipart(&form); !errors.As(err, &bindErr) || bindErr.Format != "multipart" {
+		t.Fatal("Expected multipart bind error, got: ", err)
+	}
+}
+
+// jsonArrayBody returns body that streams JSON array of n objects, produced
+// as body is read.
+func jsonArrayBody(n int) io.ReadCloser {
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, "[")
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			if _, err := io.WriteString(w, `{"id":`+strconv.Itoa(i)+`}`); err != nil {
+				return
+			}
+		}
+		io.WriteString(w, "]")
+		w.Close()
+	}()
+	return r
+}
+
+func decodeElements(decoder *json.Decoder, each func(id int)) error {
+	if _, err := decoder.Token(); err != nil {
+		return err
+	}
+	for decoder.More() {
+		var element struct {
+			ID int `json:"id"`
+		}
+		if err := decoder.Decode(&element); err != nil {
+			return err
+		}
+		each(element.ID)
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+func TestDecodeStream(t *testing.T) {
+	const n = 100000
+	body := jsonArrayBody(n)
+	defer body.Close()
+	request, _ := http.NewRequest("POST", "/", body)
+	c := NewContext(httptest.NewRecorder(), request)
+	count := 0
+	err := c.DecodeStream(func(decoder *json.Decoder) error {
+		return decodeElements(decoder, func(id int) {
+			if id != count {
+				t.Fatal("Wrong element: ", id)
+			}
+			count++
+		})
+	})
+	if err != nil || count != n {
+		t.Fatal("Streaming failed: ", count, err)
+	}
+}
+
+func TestDecodeStreamCancelled(t *testing.T) {
+	body := jsonArrayBody(100000)
+	defer body.Close()
+	request, _ := http.NewRequest("POST", "/", body)
+	c := NewContext(httptest.NewRecorder(), request)
+	cancel := c.WithCancel()
+	count := 0
+	err := c.DecodeStream(func(decoder *json.Decoder) error {
+		return decodeElements(decoder, func(id int) {
+			count++
+			if id == 0 {
+				cancel()
+			}
+		})
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("Expected cancellation error, got: ", err)
+	}
+	if count == 100000 {
+		t.Fatal("Decoding not stopped after cancellation.")
+	}
+}
@@ -0,0 +1,145 @@
+package mezvaro
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Body reads and returns entire body of request. Read body is cached in
+// context and request body is replaced with reader over cached copy, so body
+// can be read again by following handlers, for example for binding. This is
+// useful for middlewares that need raw bytes of body, like signature
+// verification. Limits on body size applied to request body (for example
+// with http.MaxBytesReader) are respected.
+func (c *Context) Body() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.body != nil {
+		return c.body, nil
+	}
+	if c.Request == nil || c.Request.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	c.body = body
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// MaxTeeSize is maximal number of bytes of request body mirrored by TeeBody.
+// Rest of the body is still readable by handlers, but it is not mirrored.
+var MaxTeeSize int64 = 64 * 1024
+
+// TeeBody mirrors request body to w as it is read by handlers, for example
+// for audit logging. Unlike Body, body is not cached, so it is suitable for
+// observing bodies without keeping them in memory. Only first MaxTeeSize
+// bytes are mirrored. Errors returned by w are ignored, so they do not
+// affect reading of body.
+func (c *Context) TeeBody(w io.Writer) {
+	if c.Request == nil || c.Request.Body == nil {
+		return
+	}
+	c.Request.Body = &teeBody{
+		Reader: io.TeeReader(c.Request.Body, &limitedWriter{w: w, remaining: MaxTeeSize}),
+		Closer: c.Request.Body,
+	}
+}
+
+// teeBody is request body that mirrors data read from it.
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
+// limitedWriter writes at most remaining bytes to underlying writer and
+// silently discards the rest.
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	failed    bool
+}
+
+// Write implements io.Writer interface. It always reports that whole data
+// has been written.
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if l.failed || l.remaining <= 0 {
+		return n, nil
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	written, err := l.w.Write(p)
+	l.remaining -= int64(written)
+	if err != nil {
+		l.failed = true
+	}
+	return n, nil
+}
+
+// copyBufferPool holds buffers used by CopyBodyTo.
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, streamBufferSize)
+		return &buf
+	},
+}
+
+// CopyBodyTo copies request body to w, for example when uploading it to
+// object storage, and returns number of bytes copied. Copying stops when
+// context is done, in which case context error is returned. Limits on body
+// size applied to request body (for example with MaxBodySize) are respected,
+// so error of exceeded limit is returned as *http.MaxBytesError. Buffers used
+// for copying are pooled between requests.
+func (c *Context) CopyBodyTo(w io.Writer) (int64, error) {
+	if c.Request == nil || c.Request.Body == nil {
+		return 0, nil
+	}
+	buf := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(buf)
+	return io.CopyBuffer(w, &contextReader{c: c, r: c.Request.Body}, *buf)
+}
+
+// MaxBodySize returns middleware that limits size of request body to n bytes.
+// Requests that declare larger Content-Length are rejected right away with
+// 413 Request Entity Too Large status and chain is aborted. For other
+// requests, reading more than n bytes of body fails with *http.MaxBytesError,
+// which SendError maps to 413 status, so handlers that pass errors of reading
+// or binding body to SendError respond with clean 413 response.
+func MaxBodySize(n int64) Handler {
+	return HandlerFunc(func(c *Context) {
+		if c.Request.ContentLength > n {
+			c.SendError(&http.MaxBytesError{Limit: n})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, n)
+		}
+		c.Next()
+	})
+}
+
+// MaxDrainSize is maximal number of bytes of request body that is read and
+// discarded after chain is aborted.
+var MaxDrainSize int64 = 256 << 10
+
+// drainBody reads and discards rest of request body, up to MaxDrainSize
+// bytes. When chain is aborted before body is read, for example because
+// authentication failed, unread body would prevent connection from being
+// reused for next request. Body is not drained if client waits for 100
+// Continue that has not been sent, since reading would ask client to send it.
+func (c *Context) drainBody() {
+	if c.Request == nil || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return
+	}
+	if c.ExpectsContinue() && !c.continueSent {
+		return
+	}
+	io.CopyN(io.Discard, c.Request.Body, MaxDrainSize)
+}
@@ -0,0 +1,215 @@
+package mezvaro
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBody(t *testing.T) {
+	const payload = `{"name": "mezvaro"}`
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(payload))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	body, err := c.Body()
+	if err != nil {
+		t.Fatal("Reading body failed: ", err)
+	}
+	if string(body) != payload {
+		t.Fatal("Wrong body: ", string(body))
+	}
+	again, _ := c.Body()
+	if string(again) != payload {
+		t.Fatal("Body not cached: ", string(again))
+	}
+
+	var bound struct {
+		Name string `json:"name"`
+	}
+	if err := c.BindJSON(&bound); err != nil {
+		t.Fatal("Binding from cached body failed: ", err)
+	}
+	if bound.Name != "mezvaro" {
+		t.Fatal("Wrong bound value: ", bound.Name)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/", strings.NewReader("too large body"))
+	request.Body = http.MaxBytesReader(response, request.Body, 4)
+	c := newContext(response, request, nil, nil)
+	_, err := c.Body()
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		t.Fatal("Body size limit not respected, got: ", err)
+	}
+}
+
+func TestBodyWithoutRequest(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	body, err := c.Body()
+	if body != nil || err != nil {
+		t.Fatal("Expected empty body without error.")
+	}
+}
+
+func TestTeeBody(t *testing.T) {
+	var mirrored bytes.Buffer
+	var bound map[string]string
+	m := New(HandlerFunc(func(c *Context) {
+		c.TeeBody(&mirrored)
+		c.Next()
+	}), HandlerFunc(func(c *Context) {
+		c.BindJSON(&bound)
+	}))
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(`{"name":"mezvaro"}`))
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if bound["name"] != "mezvaro" {
+		t.Fatal("Body not readable by handler: ", bound)
+	}
+	if mirrored.String() != `{"name":"mezvaro"}` {
+		t.Fatal("Body not mirrored: ", mirrored.String())
+	}
+}
+
+func TestTeeBodyLimit(t *testing.T) {
+	previous := MaxTeeSize
+	MaxTeeSize = 4
+	defer func() { MaxTeeSize = previous }()
+
+	var mirrored bytes.Buffer
+	request, _ := http.NewRequest("POST", "/", strings.NewReader("0123456789"))
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	c.TeeBody(&mirrored)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil || string(body) != "0123456789" {
+		t.Fatal("Whole body not readable: ", string(body), err)
+	}
+	if mirrored.String() != "0123" {
+		t.Fatal("Mirrored body not limited: ", mirrored.String())
+	}
+}
+
+func TestCopyBodyTo(t *testing.T) {
+	data := strings.Repeat("mezvaro", 10000)
+	request := httptest.NewRequest("PUT", "/", strings.NewReader(data))
+	c := NewContext(httptest.NewRecorder(), request)
+	var out bytes.Buffer
+	n, err := c.CopyBodyTo(&out)
+	if err != nil || n != int64(len(data)) {
+		t.Fatal("Wrong result of copy: ", n, err)
+	}
+	if out.String() != data {
+		t.Fatal("Copied body does not match request body.")
+	}
+}
+
+func TestCopyBodyToLimit(t *testing.T) {
+	var err error
+	m := New(MaxBodySize(16))
+	m.UseFunc(func(c *Context) {
+		_, err = c.CopyBodyTo(io.Discard)
+	})
+	request, _ := http.NewRequest("PUT", "/", io.MultiReader(strings.NewReader(strings.Repeat("a", 32))))
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		t.Fatal("Size limit not respected: ", err)
+	}
+}
+
+func TestCopyBodyToCancelled(t *testing.T) {
+	request := httptest.NewRequest("PUT", "/", nil)
+	c := NewContext(httptest.NewRecorder(), request)
+	cancel := c.WithCancel()
+	// body that cancels context after first chunk is read
+	request.Body = io.NopCloser(io.MultiReader(
+		strings.NewReader("first"),
+		readerFunc(func(p []byte) (int, error) {
+			cancel()
+			return copy(p, "second"), nil
+		}),
+		strings.NewReader("third"),
+	))
+	var out bytes.Buffer
+	n, err := c.CopyBodyTo(&out)
+	if err == nil {
+		t.Fatal("Copying not stopped on cancel.")
+	}
+	if out.String() != "firstsecond" || n != int64(out.Len()) {
+		t.Fatal("Wrong data copied before cancel: ", out.String(), n)
+	}
+}
+
+// readerFunc is io.Reader implemented by function.
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	m := New(MaxBodySize(16))
+	m.UseFunc(func(c *Context) {
+		var v map[string]string
+		if err := c.BindJSON(&v); err != nil {
+			c.SendError(err)
+			return
+		}
+		c.Response.WriteHeader(http.StatusNoContent)
+	})
+	for _, tc := range []struct {
+		body          string
+		contentLength int64
+		status        int
+	}{
+		{`{"a": "b"}`, -1, http.StatusNoContent},
+		{`{"name": "very long value"}`, -1, http.StatusRequestEntityTooLarge},
+		{`{"name": "very long value"}`, 27, http.StatusRequestEntityTooLarge},
+	} {
+		// reader without length, so size is known only while reading
+		request, _ := http.NewRequest("POST", "/", io.MultiReader(strings.NewReader(tc.body)))
+		request.ContentLength = tc.contentLength
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		if response.Code != tc.status {
+			t.Fatal("Wrong status for ", tc.body, ": ", response.Code)
+		}
+	}
+}
+
+func TestBodyDrainedOnAbort(t *testing.T) {
+	previous := MaxDrainSize
+	MaxDrainSize = 8
+	defer func() { MaxDrainSize = previous }()
+	m := New(HandlerFunc(func(c *Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}))
+	for _, tc := range []struct {
+		body      string
+		remaining int
+	}{
+		{"payload", 0},
+		{"larger payload", 6},
+	} {
+		body := strings.NewReader(tc.body)
+		request, _ := http.NewRequest("POST", "/", body)
+		m.ServeHTTP(httptest.NewRecorder(), request)
+		if body.Len() != tc.remaining {
+			t.Fatal("Body not drained after abort: ", body.Len())
+		}
+	}
+}
+
+func TestBodyNotDrainedWithoutAbort(t *testing.T) {
+	body := strings.NewReader("payload")
+	request, _ := http.NewRequest("POST", "/", body)
+	New(HandlerFunc(func(c *Context) {})).ServeHTTP(httptest.NewRecorder(), request)
+	if body.Len() != len("payload") {
+		t.Fatal("Body drained without abort.")
+	}
+}
@@ -0,0 +1,147 @@
+package mezvaro
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ErrCircuitOpen is returned for calls rejected by open circuit breaker.
+var ErrCircuitOpen = errors.New("mezvaro: circuit breaker is open")
+
+// Defaults for circuit breakers created by Call for names without registered
+// breaker.
+var (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+var (
+	breakersLock sync.Mutex
+	breakers     = make(map[string]*CircuitBreaker)
+)
+
+// CircuitBreaker protects calls to downstream service from cascading
+// failures. After threshold of consecutive failed calls, breaker opens and
+// rejects calls with ErrCircuitOpen without executing them. When cooldown
+// passes, single probe call is let through (breaker is half-open). If it
+// succeeds, breaker closes, otherwise it opens for another cooldown.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	probing   bool
+}
+
+// NewCircuitBreaker creates circuit breaker that opens after threshold
+// consecutive failures and stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// RegisterCircuitBreaker registers breaker under provided name, for use by
+// Call. It replaces breaker previously registered under the same name.
+func RegisterCircuitBreaker(name string, breaker *CircuitBreaker) {
+	breakersLock.Lock()
+	defer breakersLock.Unlock()
+	breakers[name] = breaker
+}
+
+// circuitBreaker returns breaker registered under provided name, registering
+// breaker with default settings if there is none.
+func circuitBreaker(name string) *CircuitBreaker {
+	breakersLock.Lock()
+	defer breakersLock.Unlock()
+	breaker, ok := breakers[name]
+	if !ok {
+		breaker = NewCircuitBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown)
+		breakers[name] = breaker
+	}
+	return breaker
+}
+
+// Do executes fn with provided context, unless breaker is open, in which
+// case ErrCircuitOpen is returned. Error returned by fn counts as failure,
+// unless context has been cancelled, since cancelled calls say nothing about
+// health of downstream service, so they do not change state of breaker. Panic
+// of fn counts as failure and is propagated to caller.
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	panicked := true
+	defer func() {
+		if panicked {
+			b.record(true)
+		}
+	}()
+	err := fn(ctx)
+	panicked = false
+	if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+		b.release()
+		return err
+	}
+	b.record(err != nil)
+	return err
+}
+
+// allow checks if call can be executed, and if it is probe of half-open
+// breaker, marks that probe is in progress.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// release ends probe of half-open breaker, if it is in progress, without
+// changing state of breaker.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// record updates state of breaker with result of call.
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = time.Now()
+	}
+}
+
+// Call executes fn with context of request, protected by circuit breaker with
+// provided name (see CircuitBreaker). Breakers are shared across requests by
+// name, so all handlers calling the same downstream service should use the
+// same name. Breaker can be configured with RegisterCircuitBreaker, otherwise
+// breaker with DefaultBreakerThreshold and DefaultBreakerCooldown is used.
+// If breaker is open, fn is not executed and returned error wraps
+// ErrCircuitOpen.
+func (c *Context) Call(name string, fn func(context.Context) error) error {
+	err := circuitBreaker(name).Do(c.Ctx(), fn)
+	if err == ErrCircuitOpen {
+		return fmt.Errorf("%w: %s", ErrCircuitOpen, name)
+	}
+	return err
+}
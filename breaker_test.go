@@ -0,0 +1,132 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+var errDownstream = errors.New("downstream failed")
+
+func TestCircuitBreakerTrips(t *testing.T) {
+	RegisterCircuitBreaker("trips", NewCircuitBreaker(2, time.Hour))
+	c := NewContext(httptest.NewRecorder(), nil)
+	calls := 0
+	failing := func(ctx context.Context) error {
+		calls++
+		return errDownstream
+	}
+	for i := 0; i < 2; i++ {
+		if err := c.Call("trips", failing); err != errDownstream {
+			t.Fatal("Expected downstream error, got: ", err)
+		}
+	}
+	err := c.Call("trips", failing)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("Expected open circuit, got: ", err)
+	}
+	if err.Error() != "mezvaro: circuit breaker is open: trips" {
+		t.Fatal("Wrong error message: ", err)
+	}
+	if calls != 2 {
+		t.Fatal("Call executed while circuit is open.")
+	}
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	RegisterCircuitBreaker("resets", NewCircuitBreaker(2, time.Hour))
+	c := NewContext(httptest.NewRecorder(), nil)
+	results := []error{errDownstream, nil, errDownstream, nil}
+	for _, result := range results {
+		result := result
+		if err := c.Call("resets", func(ctx context.Context) error { return result }); err != result {
+			t.Fatal("Circuit opened without consecutive failures: ", err)
+		}
+	}
+}
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	RegisterCircuitBreaker("half-open", NewCircuitBreaker(1, 20*time.Millisecond))
+	c := NewContext(httptest.NewRecorder(), nil)
+	c.Call("half-open", func(ctx context.Context) error { return errDownstream })
+	time.Sleep(30 * time.Millisecond)
+
+	// failed probe opens circuit again
+	if err := c.Call("half-open", func(ctx context.Context) error { return errDownstream }); err != errDownstream {
+		t.Fatal("Probe not executed after cooldown: ", err)
+	}
+	if err := c.Call("half-open", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("Circuit not opened after failed probe: ", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	// successful probe closes circuit
+	if err := c.Call("half-open", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal("Probe failed: ", err)
+	}
+	if err := c.Call("half-open", func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal("Circuit not closed after successful probe: ", err)
+	}
+}
+
+func TestCircuitBreakerUsesRequestContext(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	c.WithValue("key", "value")
+	cancel := c.WithCancel()
+	cancel()
+	for i := 0; i < DefaultBreakerThreshold+1; i++ {
+		err := c.Call("cancelled", func(ctx context.Context) error {
+			if ctx.Value("key") != "value" {
+				t.Fatal("Call does not use request context.")
+			}
+			return ctx.Err()
+		})
+		if err != context.Canceled {
+			t.Fatal("Cancelled calls opened circuit: ", err)
+		}
+	}
+}
+
+func TestCircuitBreakerCancelledProbe(t *testing.T) {
+	breaker := NewCircuitBreaker(2, 20*time.Millisecond)
+	ctx := context.Background()
+	breaker.Do(ctx, func(ctx context.Context) error { return errDownstream })
+	breaker.Do(ctx, func(ctx context.Context) error { return errDownstream })
+	time.Sleep(30 * time.Millisecond)
+
+	// cancelled probe neither closes circuit nor blocks next probe
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := breaker.Do(cancelled, func(ctx context.Context) error { return ctx.Err() }); err != context.Canceled {
+		t.Fatal("Probe not executed after cooldown: ", err)
+	}
+	if err := breaker.Do(ctx, func(ctx context.Context) error { return errDownstream }); err != errDownstream {
+		t.Fatal("Next probe not let through after cancelled probe: ", err)
+	}
+	if err := breaker.Do(ctx, func(ctx context.Context) error { return nil }); err != ErrCircuitOpen {
+		t.Fatal("Circuit closed after cancelled probe: ", err)
+	}
+}
+
+func TestCircuitBreakerPanic(t *testing.T) {
+	breaker := NewCircuitBreaker(1, 20*time.Millisecond)
+	ctx := context.Background()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Panic not propagated.")
+			}
+		}()
+		breaker.Do(ctx, func(ctx context.Context) error { panic("boom") })
+	}()
+	if err := breaker.Do(ctx, func(ctx context.Context) error { return nil }); err != ErrCircuitOpen {
+		t.Fatal("Panic not counted as failure: ", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if err := breaker.Do(ctx, func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatal("Probe not let through after panic: ", err)
+	}
+}
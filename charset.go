@@ -0,0 +1,142 @@
+package mezvaro
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupportedCharset is returned by binders when charset transcoding is
+// enabled (see WithCharsetTranscoding) and request body is encoded in charset
+// that can not be transcoded to UTF-8.
+var ErrUnsupportedCharset = errors.New("mezvaro: unsupported charset")
+
+// windows1252 maps bytes 0x80-0x9f of Windows-1252 encoding to runes. Other
+// bytes map to runes with the same value, like in ISO-8859-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008d', 'Ž', '\u008f',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009d', 'ž', 'Ÿ',
+}
+
+// WithCharsetTranscoding enables or disables transcoding of request bodies for
+// Mezvaro instance and its forks. When enabled, binders transcode bodies
+// declared as ISO-8859-1 or Windows-1252 in Content-Type header to UTF-8
+// before decoding them, and fail with error that wraps ErrUnsupportedCharset
+// for other charsets that are not UTF-8. By default bodies are decoded as is.
+func WithCharsetTranscoding(enabled bool) Option {
+	return func(m *Mezvaro) {
+		m.transcodeCharset = enabled
+	}
+}
+
+// isCharsetTranscoded checks if charset transcoding is enabled for this
+// instance or any of its parents.
+func (m *Mezvaro) isCharsetTranscoded() bool {
+	for current := m; current != nil; current = current.parent {
+		if current.transcodeCharset {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentType returns media type from Content-Type header of request,
+// lower cased and without parameters, for example "application/json". Empty
+// string is returned if header is missing or it can not be parsed.
+func (c *Context) ContentType() string {
+	if c.Request == nil {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return mediaType
+}
+
+// Charset returns lower cased charset parameter of Content-Type header of
+// request. Empty string is returned if charset is not provided or header
+// can not be parsed.
+func (c *Context) Charset() string {
+	if c.Request == nil {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(params["charset"])
+}
+
+// bodyReader returns reader of request body, transcoded to UTF-8 if charset
+// transcoding is enabled.
+func (c *Context) bodyReader() (io.Reader, error) {
+	if c.mezvaro == nil || !c.mezvaro.isCharsetTranscoded() {
+		return c.Request.Body, nil
+	}
+	switch charset := c.Charset(); charset {
+	case "", "utf-8", "utf8", "us-ascii":
+		return c.Request.Body, nil
+	case "iso-8859-1", "latin1", "l1":
+		return &singleByteReader{r: c.Request.Body}, nil
+	case "windows-1252", "cp1252":
+		return &singleByteReader{r: c.Request.Body, high: &windows1252}, nil
+	default:
+		return nil, &BindError{
+			Format:  "charset",
+			Message: "unsupported charset " + charset,
+			Err:     ErrUnsupportedCharset,
+		}
+	}
+}
+
+// singleByteReader transcodes text in single byte encoding to UTF-8.
+type singleByteReader struct {
+	r io.Reader
+	// high maps bytes 0x80-0x9f, if nil bytes are mapped to runes with
+	// the same value.
+	high    *[32]rune
+	src     []byte
+	pending []byte
+	// err is error returned by underlying reader together with data, it
+	// is returned once transcoded data is read.
+	err error
+}
+
+// Read implements io.Reader interface.
+func (r *singleByteReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.src == nil {
+			r.src = make([]byte, 4096)
+		}
+		n, err := r.r.Read(r.src)
+		r.err = err
+		r.pending = r.pending[:0]
+		for _, b := range r.src[:n] {
+			switch {
+			case b < utf8.RuneSelf:
+				r.pending = append(r.pending, b)
+			case r.high != nil && b < 0xa0:
+				r.pending = utf8.AppendRune(r.pending, r.high[b-0x80])
+			default:
+				r.pending = utf8.AppendRune(r.pending, rune(b))
+			}
+		}
+		if n == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	if len(r.pending) == 0 && r.err != nil {
+		return n, r.err
+	}
+	return n, nil
+}
@@ -0,0 +1,116 @@
+package mezvaro
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/iotest"
+)
+
+func TestCharset(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", nil)
+	request.Header.Set("Content-Type", "application/json; charset=ISO-8859-1")
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	if charset := c.Charset(); charset != "iso-8859-1" {
+		t.Fatal("Wrong charset: ", charset)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if charset := c.Charset(); charset != "" {
+		t.Fatal("Expected empty charset, got: ", charset)
+	}
+}
+
+func bindTranscoded(contentType string, body []byte) (string, error) {
+	var target struct {
+		Name string `json:"name"`
+	}
+	var err error
+	m := New().With(WithCharsetTranscoding(true))
+	m.UseFunc(func(c *Context) {
+		err = c.BindJSON(&target)
+	})
+	request, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	return target.Name, err
+}
+
+func TestBindLatin1Transcoded(t *testing.T) {
+	// "José Müller" encoded in ISO-8859-1
+	body := []byte("{\"name\": \"Jos\xe9 M\xfcller\"}")
+	name, err := bindTranscoded("application/json; charset=iso-8859-1", body)
+	if err != nil {
+		t.Fatal("Binding failed: ", err)
+	}
+	if name != "José Müller" {
+		t.Fatal("Body not transcoded to UTF-8: ", name)
+	}
+}
+
+func TestBindWindows1252Transcoded(t *testing.T) {
+	body := []byte("{\"name\": \"\x93price\x94 \x80\"}")
+	name, err := bindTranscoded("application/json; charset=windows-1252", body)
+	if err != nil {
+		t.Fatal("Binding failed: ", err)
+	}
+	if name != "“price” €" {
+		t.Fatal("Body not transcoded to UTF-8: ", name)
+	}
+}
+
+func TestBindUnsupportedCharset(t *testing.T) {
+	_, err := bindTranscoded("application/json; charset=koi8-r", []byte(`{"name": "x"}`))
+	if !errors.Is(err, ErrUnsupportedCharset) {
+		t.Fatal("Expected unsupported charset error, got: ", err)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := map[string]string{
+		"application/json":                  "application/json",
+		" Application/JSON ; charset=UTF-8": "application/json",
+		"multipart/form-data; boundary=xyz": "multipart/form-data",
+		"":                                  "",
+		"application/":                      "",
+	}
+	for header, expected := range tests {
+		request, _ := http.NewRequest("POST", "/", nil)
+		request.Header.Set("Content-Type", header)
+		c := newContext(httptest.NewRecorder(), request, nil, nil)
+		if contentType := c.ContentType(); contentType != expected {
+			t.Fatal("Wrong content type for ", header, ": ", contentType)
+		}
+	}
+}
+
+// dataErrReader returns data together with error, like some readers do.
+type dataErrReader struct {
+	data []byte
+	err  error
+}
+
+func (r *dataErrReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, r.err
+}
+
+func TestSingleByteReaderDataWithError(t *testing.T) {
+	data, err := io.ReadAll(&singleByteReader{r: iotest.DataErrReader(bytes.NewReader([]byte("caf\xe9")))})
+	if err != nil || string(data) != "café" {
+		t.Fatal("Data returned with EOF not transcoded: ", string(data), err)
+	}
+
+	broken := errors.New("connection reset")
+	r := &singleByteReader{r: &dataErrReader{data: []byte("caf\xe9"), err: broken}}
+	data, err = io.ReadAll(r)
+	if err != broken || string(data) != "café" {
+		t.Fatal("Error returned with data lost: ", string(data), err)
+	}
+	if _, err := r.Read(make([]byte, 8)); err != broken {
+		t.Fatal("Error not returned by following reads: ", err)
+	}
+}
@@ -0,0 +1,44 @@
+package mezvaro
+
+import (
+	"crypto/tls"
+	"net/http"
+)
+
+// ClientCertValidator validates client certificate chain presented in TLS
+// connection and derives identity of the client from it. If chain is not
+// valid, ok has to be false.
+type ClientCertValidator func(*tls.ConnectionState) (identity string, ok bool)
+
+// ClientCertAuth creates middleware that authenticates clients based on TLS
+// client certificates. Requests that are not made over TLS or that do not
+// present any certificate are rejected with 401 Unauthorized status, requests
+// whose certificate chain is rejected by validator are rejected with 403
+// Forbidden status. In both cases chain is aborted.
+//
+// Identity returned by validator is stored in context and can be obtained
+// with ClientCertIdentity method.
+func ClientCertAuth(validator ClientCertValidator) Handler {
+	return HandlerFunc(func(c *Context) {
+		if c.Request == nil || c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+			http.Error(c.Response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		identity, ok := validator(c.Request.TLS)
+		if !ok {
+			http.Error(c.Response, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			c.Abort()
+			return
+		}
+		clientCertIdentityKey.Set(c, identity)
+		c.Next()
+	})
+}
+
+// ClientCertIdentity returns identity of client derived by ClientCertAuth
+// middleware. If middleware was not used, empty string is returned.
+func (c *Context) ClientCertIdentity() string {
+	identity, _ := clientCertIdentityKey.Get(c)
+	return identity
+}
@@ -0,0 +1,74 @@
+package mezvaro
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func commonNameValidator(state *tls.ConnectionState) (string, bool) {
+	cn := state.PeerCertificates[0].Subject.CommonName
+	return cn, cn == "trusted"
+}
+
+func requestWithClientCert(commonName string) *http.Request {
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{
+			{Subject: pkix.Name{CommonName: commonName}},
+		},
+	}
+	return request
+}
+
+func TestClientCertAuthValid(t *testing.T) {
+	var identity string
+	m := New(ClientCertAuth(commonNameValidator))
+	m.UseFunc(func(c *Context) {
+		identity = c.ClientCertIdentity()
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, requestWithClientCert("trusted"))
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected status 200, got: ", response.Code)
+	}
+	if identity != "trusted" {
+		t.Fatal("Identity not stored in context, got: ", identity)
+	}
+}
+
+func TestClientCertAuthInvalid(t *testing.T) {
+	var called bool
+	m := New(ClientCertAuth(commonNameValidator))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, requestWithClientCert("intruder"))
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected status 403, got: ", response.Code)
+	}
+	if called {
+		t.Fatal("Handler called for invalid certificate.")
+	}
+}
+
+func TestClientCertAuthNoTLS(t *testing.T) {
+	var called bool
+	m := New(ClientCertAuth(commonNameValidator))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected status 401, got: ", response.Code)
+	}
+	if called {
+		t.Fatal("Handler called for request without TLS.")
+	}
+}
@@ -0,0 +1,278 @@
+package mezvaro
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Encoder creates writer that compresses data written to it and writes
+// compressed data to w. Returned writer is closed when response is finished.
+type Encoder func(w io.Writer) io.WriteCloser
+
+// encoderEntry is content coding supported by Compress middleware.
+type encoderEntry struct {
+	name    string
+	encoder Encoder
+}
+
+var (
+	encodersLock sync.RWMutex
+	encoders     = []encoderEntry{{name: "gzip", encoder: func(w io.Writer) io.WriteCloser {
+		return gzip.NewWriter(w)
+	}}}
+)
+
+// RegisterEncoder registers encoder for content coding with provided name
+// (for example "br") for use by Compress middleware, replacing encoder
+// previously registered under the same name. Only gzip is supported out of
+// the box, so core library does not depend on third party compression
+// libraries. Brotli can be added by registering encoder from brotli library:
+//
+//	mezvaro.RegisterEncoder("br", func(w io.Writer) io.WriteCloser {
+//		return brotli.NewWriter(w)
+//	})
+//
+// When client accepts multiple codings with the same quality, encoders
+// registered later are preferred.
+func RegisterEncoder(name string, encoder Encoder) {
+	encodersLock.Lock()
+	defer encodersLock.Unlock()
+	name = strings.ToLower(name)
+	for i := range encoders {
+		if encoders[i].name == name {
+			encoders = append(encoders[:i], encoders[i+1:]...)
+			break
+		}
+	}
+	encoders = append(encoders, encoderEntry{name: name, encoder: encoder})
+}
+
+// CompressibleTypes are media types of responses compressed by Compress
+// middleware. Wildcards like "text/*" are supported. Other responses, like
+// images and archives, are already compressed, so compressing them again only
+// wastes CPU. It should be changed before server starts.
+var CompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/problem+json",
+	"application/xml",
+	"application/javascript",
+	"image/svg+xml",
+}
+
+// MinCompressSize is minimal size of response body, in bytes, compressed by
+// Compress middleware. Compressing smaller responses does not pay off.
+var MinCompressSize = 1024
+
+// Compress returns middleware that compresses responses with content coding
+// client prefers, according to quality values in Accept-Encoding header of
+// request, among codings with registered encoder (see RegisterEncoder). If
+// client does not accept any of them, response is not compressed. Responses
+// that already have Content-Encoding header set by handlers are not
+// compressed again. Only responses with one of CompressibleTypes that have
+// at least MinCompressSize bytes of body are compressed, so body is buffered
+// until that size is reached. Responses flushed before that are sent
+// uncompressed. Vary header is always extended with Accept-Encoding. If rest
+// of chain panics, buffered response is discarded, so recovery middleware
+// before Compress can respond with error instead.
+func Compress() Handler {
+	return HandlerFunc(func(c *Context) {
+		c.Response.Header().Add("Vary", "Accept-Encoding")
+		name, encoder := c.preferredEncoder()
+		if encoder == nil {
+			c.Next()
+			return
+		}
+		original := c.Response
+		cw := &compressWriter{ResponseWriter: original, name: name, encoder: encoder}
+		c.SetResponse(cw)
+		defer func() {
+			if p := recover(); p != nil {
+				// let recovery respond through original writer, instead
+				// of into compressed stream
+				cw.abort()
+				c.Response = original
+				panic(p)
+			}
+			cw.close()
+			c.Response = original
+		}()
+		c.Next()
+	})
+}
+
+// preferredEncoder returns name and encoder of registered content coding
+// with highest quality in Accept-Encoding header of request. If none of them
+// is accepted, nil encoder is returned.
+func (c *Context) preferredEncoder() (string, Encoder) {
+	encodersLock.RLock()
+	defer encodersLock.RUnlock()
+	var best encoderEntry
+	bestQuality := 0.0
+	for i := len(encoders) - 1; i >= 0; i-- {
+		if q := encodingQuality(c.Request, encoders[i].name); q > bestQuality {
+			best, bestQuality = encoders[i], q
+		}
+	}
+	return best.name, best.encoder
+}
+
+// compressWriter compresses body written to it. Status and beginning of body
+// are buffered until it is known whether response should be compressed, so
+// handlers can still opt out by setting Content-Encoding header themselves.
+type compressWriter struct {
+	http.ResponseWriter
+	name       string
+	encoder    Encoder
+	status     int
+	buf        []byte
+	decided    bool
+	compressed io.WriteCloser
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || status < http.StatusOK {
+		// informational responses are not final, so they are sent
+		// right away
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if w.decided {
+		return w.write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < MinCompressSize {
+		return len(b), nil
+	}
+	if err := w.decide(true); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// write writes body to compressed stream, if response is compressed, or
+// directly to underlying writer.
+func (w *compressWriter) write(b []byte) (int, error) {
+	if w.compressed != nil {
+		return w.compressed.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// decide sets up compression of response, if compress is true and response
+// has compressible body that is not encoded already, and writes buffered
+// status and body.
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		// content type can not be detected from compressed data, so
+		// detect it before compression
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	if compress && bodyAllowed(w.status) && header.Get("Content-Encoding") == "" &&
+		compressibleType(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", w.name)
+		header.Del("Content-Length")
+		w.compressed = w.encoder(w.ResponseWriter)
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+// compressibleType checks if content type is one of CompressibleTypes.
+func compressibleType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" {
+		return false
+	}
+	for _, t := range CompressibleTypes {
+		if mediaTypeMatches(strings.ToLower(t), mediaType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush implements http.Flusher interface, flushing compressed data first,
+// if encoder supports it.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.decide(false)
+	}
+	if flusher, ok := w.compressed.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports
+// it. Buffered response is discarded, since connection is taken over.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil {
+		w.decided = true
+		w.buf = nil
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns underlying response writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// abort discards buffered response, so response can be written directly to
+// underlying writer, or finishes compressed stream, if response has already
+// been written.
+func (w *compressWriter) abort() {
+	if !w.decided {
+		w.decided = true
+		w.buf = nil
+		return
+	}
+	if w.compressed != nil {
+		w.compressed.Close()
+		w.compressed = nil
+	}
+}
+
+// close writes buffered response and finishes compressed stream, if
+// response has been compressed.
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.compressed != nil {
+		w.compressed.Close()
+	}
+}
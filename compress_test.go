@@ -0,0 +1,270 @@
+package mezvaro
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// prefixEncoder is fake encoder that marks data instead of compressing it.
+type prefixEncoder struct {
+	w       io.Writer
+	started bool
+}
+
+func (e *prefixEncoder) Write(b []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		io.WriteString(e.w, "br:")
+	}
+	return e.w.Write(b)
+}
+
+func (e *prefixEncoder) Close() error { return nil }
+
+func withFakeBrotli(t *testing.T) {
+	encodersLock.Lock()
+	previous := append([]encoderEntry(nil), encoders...)
+	encodersLock.Unlock()
+	t.Cleanup(func() {
+		encodersLock.Lock()
+		encoders = previous
+		encodersLock.Unlock()
+	})
+	RegisterEncoder("br", func(w io.Writer) io.WriteCloser {
+		return &prefixEncoder{w: w}
+	})
+}
+
+// compressibleBody is HTML body large enough to be compressed.
+var compressibleBody = "<html>" + strings.Repeat("hello", 300) + "</html>"
+
+func compressed(acceptEncoding string) *httptest.ResponseRecorder {
+	m := New(Compress())
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte(compressibleBody))
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	if acceptEncoding != "" {
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response
+}
+
+func TestCompressGzip(t *testing.T) {
+	response := compressed("gzip, deflate")
+	if ce := response.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatal("Wrong content encoding: ", ce)
+	}
+	if vary := response.Header().Get("Vary"); vary != "Accept-Encoding" {
+		t.Fatal("Vary header not set: ", vary)
+	}
+	if ct := response.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatal("Content type not detected from uncompressed body: ", ct)
+	}
+	reader, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatal("Body is not gzip compressed: ", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != compressibleBody {
+		t.Fatal("Wrong decompressed body: ", string(body))
+	}
+}
+
+func TestCompressNotAccepted(t *testing.T) {
+	response := compressed("")
+	if ce := response.Header().Get("Content-Encoding"); ce != "" {
+		t.Fatal("Response compressed for client without Accept-Encoding: ", ce)
+	}
+	if response.Body.String() != compressibleBody {
+		t.Fatal("Wrong body: ", response.Body.String())
+	}
+}
+
+func TestCompressBrotliPreferred(t *testing.T) {
+	withFakeBrotli(t)
+	response := compressed("gzip, deflate, br")
+	if ce := response.Header().Get("Content-Encoding"); ce != "br" {
+		t.Fatal("Brotli not preferred for equal quality: ", ce)
+	}
+	if response.Body.String() != "br:"+compressibleBody {
+		t.Fatal("Body not encoded with brotli: ", response.Body.String())
+	}
+}
+
+func TestCompressFollowsClientQuality(t *testing.T) {
+	withFakeBrotli(t)
+	if ce := compressed("br;q=0.5, gzip").Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatal("Client preference for gzip ignored: ", ce)
+	}
+	if ce := compressed("gzip").Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatal("Gzip only client did not receive gzip: ", ce)
+	}
+}
+
+func TestCompressAlreadyEncoded(t *testing.T) {
+	m := New(Compress())
+	m.UseFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Encoding", "gzip")
+		c.Response.Write([]byte("precompressed"))
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Body.String() != "precompressed" {
+		t.Fatal("Encoded response compressed again.")
+	}
+}
+
+func serveCompressed(contentType string, body []byte) *httptest.ResponseRecorder {
+	m := New(Compress())
+	m.UseFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", contentType)
+		c.Response.WriteHeader(http.StatusCreated)
+		// write in small chunks, so decision is made only after buffering
+		for len(body) > 0 {
+			n := 100
+			if n > len(body) {
+				n = len(body)
+			}
+			c.Response.Write(body[:n])
+			body = body[n:]
+		}
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response
+}
+
+func TestCompressLargeJSON(t *testing.T) {
+	body := "[" + strings.Repeat(`{"name":"mezvaro"},`, 100) + "{}]"
+	response := serveCompressed("application/json; charset=utf-8", []byte(body))
+	if ce := response.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatal("Large JSON response not compressed: ", ce)
+	}
+	if response.Code != http.StatusCreated {
+		t.Fatal("Wrong status: ", response.Code)
+	}
+	reader, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatal("Body is not gzip compressed: ", err)
+	}
+	decompressed, _ := io.ReadAll(reader)
+	if string(decompressed) != body {
+		t.Fatal("Wrong decompressed body.")
+	}
+}
+
+func TestCompressSmallResponse(t *testing.T) {
+	response := serveCompressed("application/json", []byte(`{"name":"mezvaro"}`))
+	if ce := response.Header().Get("Content-Encoding"); ce != "" {
+		t.Fatal("Small response compressed: ", ce)
+	}
+	if response.Code != http.StatusCreated || response.Body.String() != `{"name":"mezvaro"}` {
+		t.Fatal("Wrong response: ", response.Code, response.Body.String())
+	}
+}
+
+func TestCompressBinaryResponse(t *testing.T) {
+	body := make([]byte, 4096)
+	response := serveCompressed("image/png", body)
+	if ce := response.Header().Get("Content-Encoding"); ce != "" {
+		t.Fatal("Binary response compressed: ", ce)
+	}
+	if response.Body.Len() != len(body) {
+		t.Fatal("Wrong body length: ", response.Body.Len())
+	}
+}
+
+func TestCompressConfigurableTypes(t *testing.T) {
+	previous := CompressibleTypes
+	CompressibleTypes = append([]string{"application/octet-stream"}, previous...)
+	defer func() { CompressibleTypes = previous }()
+	response := serveCompressed("application/octet-stream", make([]byte, 4096))
+	if ce := response.Header().Get("Content-Encoding"); ce != "gzip" {
+		t.Fatal("Configured type not compressed: ", ce)
+	}
+}
+
+func TestCompressHijack(t *testing.T) {
+	m := New(Compress(), HandlerFunc(func(c *Context) {
+		hijacker, ok := c.Response.(http.Hijacker)
+		if !ok {
+			t.Error("Response writer does not implement http.Hijacker.")
+			return
+		}
+		conn, rw, err := hijacker.Hijack()
+		if err != nil {
+			t.Error("Hijacking failed: ", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: test\r\nConnection: Upgrade\r\n\r\nhijacked")
+		rw.Flush()
+	}))
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("Connection failed: ", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\nAccept-Encoding: gzip\r\nConnection: Upgrade\r\nUpgrade: test\r\n\r\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	data, _ := io.ReadAll(conn)
+	if !strings.HasPrefix(string(data), "HTTP/1.1 101") || !strings.HasSuffix(string(data), "hijacked") {
+		t.Fatal("Wrong response on hijacked connection: ", string(data))
+	}
+}
+
+func TestCompressPanicBeforeWrite(t *testing.T) {
+	m := New(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))), Compress(), HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		c.Response.Write([]byte("partial"))
+		panic("broken")
+	}))
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Wrong status after panic: ", response.Code)
+	}
+	if response.Header().Get("Content-Encoding") != "" || strings.Contains(response.Body.String(), "partial") {
+		t.Fatal("Buffered response written after panic: ", response.Body.String())
+	}
+}
+
+func TestCompressPanicAfterWrite(t *testing.T) {
+	body := strings.Repeat("mezvaro ", MinCompressSize)
+	m := New(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))), Compress(), HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		c.Response.Write([]byte(body))
+		panic("broken")
+	}))
+	request := httptest.NewRequest("GET", "/", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	reader, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatal("Response not compressed: ", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil || string(data) != body {
+		t.Fatal("Compressed stream not finished after panic: ", err)
+	}
+}
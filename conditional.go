@@ -0,0 +1,105 @@
+package mezvaro
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CheckIfMatch checks If-Match header of request against current entity tag
+// of resource, for optimistic concurrency control of writes: client sends
+// entity tag of version of resource it has modified, and write is rejected if
+// resource has been changed in the meantime. Entity tag should be quoted, like
+// "\"v42\"", unquoted tag is quoted. Empty entity tag means that resource does
+// not exist.
+//
+// Requests without If-Match header always pass. Wildcard "*" matches any
+// existing resource. Tags are compared with strong comparison, so weak tags
+// never match. If check fails, response with 412 Precondition Failed status is
+// written, chain is aborted and false is returned.
+func (c *Context) CheckIfMatch(currentETag string) bool {
+	header := c.Request.Header.Get("If-Match")
+	if header == "" || ifMatch(header, currentETag) {
+		return true
+	}
+	c.AbortWithStatus(http.StatusPreconditionFailed)
+	return false
+}
+
+// Revalidate checks whether copy of resource cached by client is still fresh,
+// based on If-None-Match and If-Modified-Since headers of GET and HEAD
+// requests. Entity tag should be quoted like in CheckIfMatch, unquoted tag is
+// quoted. Empty entity tag or zero modification time skip respective check.
+// Non-empty entity tag and non-zero modification time are also set as ETag
+// and Last-Modified headers of response.
+//
+// If-None-Match takes precedence: if it is present, If-Modified-Since is
+// ignored. Tags are compared with weak comparison and wildcard "*" matches any
+// existing resource. If client's copy is fresh, response with 304 Not
+// Modified status is written, chain is aborted and true is returned. Otherwise
+// false is returned and handler should write full response.
+func (c *Context) Revalidate(etag string, lastModified time.Time) bool {
+	if etag != "" && !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, "W/") {
+		etag = `"` + etag + `"`
+	}
+	header := c.Response.Header()
+	if etag != "" {
+		header.Set("ETag", etag)
+	}
+	if !lastModified.IsZero() {
+		header.Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	fresh := false
+	if noneMatch := c.Request.Header.Get("If-None-Match"); noneMatch != "" {
+		fresh = ifNoneMatch(noneMatch, etag)
+	} else if since, err := http.ParseTime(c.Request.Header.Get("If-Modified-Since")); err == nil && !lastModified.IsZero() {
+		// header has precision of seconds
+		fresh = !lastModified.Truncate(time.Second).After(since)
+	}
+	if !fresh {
+		return false
+	}
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// ifNoneMatch checks if value of If-None-Match header matches entity tag
+// with weak comparison.
+func ifNoneMatch(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch checks if value of If-Match header matches entity tag.
+func ifMatch(header, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	if !strings.HasPrefix(etag, `"`) && !strings.HasPrefix(etag, "W/") {
+		etag = `"` + etag + `"`
+	}
+	if strings.HasPrefix(etag, "W/") {
+		return strings.TrimSpace(header) == "*"
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
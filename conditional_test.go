@@ -0,0 +1,100 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	for _, tc := range []struct {
+		ifMatch string
+		current string
+		matches bool
+	}{
+		{"", `"v1"`, true},
+		{`"v1"`, `"v1"`, true},
+		{`"v0", "v1"`, `"v1"`, true},
+		{`"v1"`, "v1", true},
+		{`"v1"`, `"v2"`, false},
+		{`W/"v1"`, `"v1"`, false},
+		{`"v1"`, `W/"v1"`, false},
+		{"*", `"v1"`, true},
+		{"*", `W/"v1"`, true},
+		{"*", "", false},
+		{`"v1"`, "", false},
+	} {
+		request, _ := http.NewRequest("PUT", "/", nil)
+		if tc.ifMatch != "" {
+			request.Header.Set("If-Match", tc.ifMatch)
+		}
+		response := httptest.NewRecorder()
+		c := NewContext(response, request)
+		if c.CheckIfMatch(tc.current) != tc.matches {
+			t.Fatal("Wrong result for ", tc.ifMatch, " and ", tc.current)
+		}
+		if !tc.matches && (response.Code != http.StatusPreconditionFailed || !c.IsAborted()) {
+			t.Fatal("Mismatch not rejected: ", response.Code)
+		}
+		if tc.matches && (c.Written() || c.IsAborted()) {
+			t.Fatal("Match rejected.")
+		}
+	}
+}
+
+func TestRevalidate(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 500, time.UTC)
+	for _, tc := range []struct {
+		method   string
+		header   string
+		value    string
+		etag     string
+		modified time.Time
+		fresh    bool
+	}{
+		{"GET", "If-None-Match", `"v1"`, "v1", time.Time{}, true},
+		{"GET", "If-None-Match", `W/"v1"`, `"v1"`, time.Time{}, true},
+		{"HEAD", "If-None-Match", `"v0", "v1"`, `W/"v1"`, time.Time{}, true},
+		{"GET", "If-None-Match", "*", `"v1"`, time.Time{}, true},
+		{"GET", "If-None-Match", `"v2"`, `"v1"`, time.Time{}, false},
+		{"GET", "If-None-Match", "*", "", time.Time{}, false},
+		// If-None-Match takes precedence over If-Modified-Since
+		{"GET", "If-None-Match", `"v2"`, `"v1"`, modified.Add(-time.Hour), false},
+		{"GET", "If-Modified-Since", modified.Format(http.TimeFormat), `"v1"`, modified, true},
+		{"GET", "If-Modified-Since", modified.Add(time.Hour).Format(http.TimeFormat), "", modified, true},
+		{"GET", "If-Modified-Since", modified.Add(-time.Hour).Format(http.TimeFormat), "", modified, false},
+		{"GET", "If-Modified-Since", "invalid", "", modified, false},
+		{"GET", "If-Modified-Since", modified.Format(http.TimeFormat), "", time.Time{}, false},
+		{"PUT", "If-None-Match", `"v1"`, `"v1"`, time.Time{}, false},
+		{"GET", "", "", `"v1"`, modified, false},
+	} {
+		request := httptest.NewRequest(tc.method, "/", nil)
+		if tc.header != "" {
+			request.Header.Set(tc.header, tc.value)
+		}
+		response := httptest.NewRecorder()
+		c := NewContext(response, request)
+		if fresh := c.Revalidate(tc.etag, tc.modified); fresh != tc.fresh {
+			t.Fatal("Wrong result for ", tc.header, " ", tc.value, ": ", fresh)
+		}
+		if tc.fresh && (response.Code != http.StatusNotModified || !c.IsAborted()) {
+			t.Fatal("Not modified response not written: ", response.Code)
+		}
+		if !tc.fresh && c.Written() {
+			t.Fatal("Response written for stale copy: ", response.Code)
+		}
+	}
+}
+
+func TestRevalidateHeaders(t *testing.T) {
+	modified := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	response := httptest.NewRecorder()
+	NewContext(response, httptest.NewRequest("GET", "/", nil)).Revalidate("v1", modified)
+	if etag := response.Header().Get("ETag"); etag != `"v1"` {
+		t.Fatal("Wrong ETag header: ", etag)
+	}
+	if lm := response.Header().Get("Last-Modified"); lm != "Fri, 01 Mar 2024 12:00:00 GMT" {
+		t.Fatal("Wrong Last-Modified header: ", lm)
+	}
+}
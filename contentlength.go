@@ -0,0 +1,172 @@
+package mezvaro
+
+import (
+	"bufio"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// ContentLength returns middleware that buffers response and sets accurate
+// Content-Length header before writing it, for clients and proxies that do not
+// handle chunked transfer encoding well. At most limit bytes are buffered.
+// Larger responses, and responses flushed by handlers, fall back to streaming
+// without Content-Length header.
+func ContentLength(limit int) Handler {
+	return HandlerFunc(func(c *Context) {
+		c.bufferResponse(limit, nil)
+	})
+}
+
+// bufferResponse executes rest of chain with response buffered up to limit
+// bytes. If transform is not nil, it is applied to buffered body with textual
+// content type. If rest of chain panics, buffered response is discarded, so
+// recovery middleware can respond with error instead.
+func (c *Context) bufferResponse(limit int, transform func([]byte) []byte) {
+	original := c.Response
+	bw := &bufferedWriter{
+		ResponseWriter: original,
+		limit:          limit,
+		head:           c.Request != nil && c.Request.Method == http.MethodHead,
+		transform:      transform,
+	}
+	c.SetResponse(bw)
+	defer func() {
+		if p := recover(); p != nil {
+			// let recovery respond through original writer, instead of
+			// writing partial response
+			bw.abort()
+			c.Response = original
+			panic(p)
+		}
+		bw.close()
+		c.Response = original
+	}()
+	c.Next()
+}
+
+// bufferedWriter buffers status and body of response, until response is
+// finished or limit is exceeded.
+type bufferedWriter struct {
+	http.ResponseWriter
+	limit     int
+	head      bool
+	transform func([]byte) []byte
+	status    int
+	buf       []byte
+	streaming bool
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.streaming || informational(status) {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if len(w.buf)+len(b) <= w.limit {
+		w.buf = append(w.buf, b...)
+		return len(b), nil
+	}
+	if err := w.stream(); err != nil {
+		return 0, err
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface. Flushing commits response, so
+// rest of it is streamed.
+func (w *bufferedWriter) Flush() {
+	if !w.streaming {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.stream()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports
+// it. Buffered response is discarded, since connection is taken over.
+func (w *bufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil {
+		w.streaming = true
+		w.buf = nil
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns underlying response writer.
+func (w *bufferedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// stream writes buffered status and body without Content-Length header and
+// switches to streaming rest of response.
+func (w *bufferedWriter) stream() error {
+	w.streaming = true
+	w.Header().Del("Content-Length")
+	return w.writeBuffered()
+}
+
+// writeBuffered writes buffered status and body.
+func (w *bufferedWriter) writeBuffered() error {
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// abort discards buffered status and body.
+func (w *bufferedWriter) abort() {
+	w.status = 0
+	w.buf = nil
+}
+
+// close writes buffered response with Content-Length header, unless response
+// is already streamed. Body is transformed first, if it has textual content
+// type.
+func (w *bufferedWriter) close() {
+	if w.streaming {
+		return
+	}
+	if w.transform != nil && len(w.buf) > 0 {
+		header := w.Header()
+		if header.Get("Content-Type") == "" {
+			// content type can not be detected from transformed body
+			header.Set("Content-Type", http.DetectContentType(w.buf))
+		}
+		mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+		if textualMediaType(mediaType) && header.Get("Content-Encoding") == "" {
+			w.buf = w.transform(w.buf)
+		}
+	}
+	// body of responses to HEAD requests is usually not written, so length
+	// of empty buffer does not say anything
+	if w.status != 0 && bodyAllowed(w.status) && !(w.head && len(w.buf) == 0) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(w.buf)))
+	}
+	w.writeBuffered()
+}
@@ -0,0 +1,109 @@
+package mezvaro
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func contentLengthServer(body string) *httptest.Server {
+	m := New(ContentLength(1024))
+	m.UseFunc(func(c *Context) {
+		c.Response.WriteHeader(http.StatusAccepted)
+		for _, part := range strings.SplitAfter(body, " ") {
+			c.Response.Write([]byte(part))
+		}
+	})
+	return httptest.NewServer(m)
+}
+
+func TestContentLength(t *testing.T) {
+	body := "response written in multiple parts"
+	server := contentLengthServer(body)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusAccepted {
+		t.Fatal("Wrong status: ", response.StatusCode)
+	}
+	if response.ContentLength != int64(len(body)) || len(response.TransferEncoding) != 0 {
+		t.Fatal("Wrong content length: ", response.ContentLength, response.TransferEncoding)
+	}
+	read, _ := io.ReadAll(response.Body)
+	if string(read) != body {
+		t.Fatal("Wrong body: ", string(read))
+	}
+}
+
+func TestContentLengthOversized(t *testing.T) {
+	body := strings.Repeat("large ", 1000)
+	server := contentLengthServer(body)
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	if response.ContentLength != -1 || len(response.TransferEncoding) != 1 || response.TransferEncoding[0] != "chunked" {
+		t.Fatal("Oversized response not streamed: ", response.ContentLength, response.TransferEncoding)
+	}
+	read, _ := io.ReadAll(response.Body)
+	if string(read) != body {
+		t.Fatal("Wrong body of streamed response.")
+	}
+}
+
+func TestContentLengthFlushed(t *testing.T) {
+	m := New(ContentLength(1024))
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte("first"))
+		c.Response.(http.Flusher).Flush()
+		c.Response.Write([]byte("second"))
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(response, request)
+	if cl := response.Header().Get("Content-Length"); cl != "" {
+		t.Fatal("Content-Length set for flushed response: ", cl)
+	}
+	if !response.Flushed || response.Body.String() != "firstsecond" {
+		t.Fatal("Wrong flushed response: ", response.Body.String())
+	}
+}
+
+func TestContentLengthHead(t *testing.T) {
+	m := New(ContentLength(1024))
+	m.UseFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Length", "42")
+		c.Response.WriteHeader(http.StatusOK)
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("HEAD", "/", nil)
+	m.ServeHTTP(response, request)
+	// body has not been written, so its length is not known
+	if cl := response.Header().Get("Content-Length"); cl != "42" {
+		t.Fatal("Content-Length of HEAD response changed: ", cl)
+	}
+}
+
+func TestContentLengthPanic(t *testing.T) {
+	m := New(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))), ContentLength(1024))
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte(`{"partial":`))
+		panic("broken")
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Wrong status after panic: ", response.Code)
+	}
+	if strings.Contains(response.Body.String(), "partial") {
+		t.Fatal("Partial response written after panic: ", response.Body.String())
+	}
+}
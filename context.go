@@ -1,7 +1,9 @@
 package mezvaro
 
 import (
+	"log/slog"
 	"net/http"
+	"strings"
 	"sync"
 
 	"math"
@@ -15,34 +17,141 @@ import (
 // off chance that it is needed, this can be increased.
 const MaxHandlers = int(math.MaxInt16)
 
+// ContextKey is typed key for storing values in context. Every key created
+// with NewContextKey is distinct, even if keys have the same name, so values
+// stored by different middlewares never collide, and type of values is
+// checked at compile time:
+//
+//	var UserKey = mezvaro.NewContextKey[*User]("user")
+//
+//	UserKey.Set(c, user) // in authentication middleware
+//	user, ok := UserKey.Get(c) // in handler
+type ContextKey[T any] struct {
+	name string
+}
+
+// NewContextKey creates new key for values of type T. Name is used only for
+// debugging.
+func NewContextKey[T any](name string) *ContextKey[T] {
+	return &ContextKey[T]{name: name}
+}
+
+// String returns name of key.
+func (k *ContextKey[T]) String() string {
+	return "mezvaro context key " + k.name
+}
+
+// Set stores value under key in context, like WithValue does.
+func (k *ContextKey[T]) Set(c *Context, value T) {
+	c.WithValue(k, value)
+}
+
+// Get returns value stored under key in provided context and boolean that
+// indicates if value exists. It accepts any context, so values can be read
+// from snapshots returned by Ctx and from contexts of requests as well.
+func (k *ContextKey[T]) Get(ctx context.Context) (T, bool) {
+	value, ok := ctx.Value(k).(T)
+	return value, ok
+}
+
+// Keys used by middlewares of Mezvaro.
+var (
+	clientCertIdentityKey = NewContextKey[string]("client certificate identity")
+	csrfTokenKey          = NewContextKey[string]("CSRF token")
+	propagatedHeadersKey  = NewContextKey[http.Header]("propagated headers")
+)
+
 // Context is main way of communication between handlers and with outside world.
 // Context instance carries http.Request and http.ResponseWriter objects, implements
 // x/net/context with all its features and provides some utility functions.
 // Same context object is shared between all middlewares in chain.
 type Context struct {
 	context.Context
-	Response     http.ResponseWriter
-	Request      *http.Request
-	handlerChain []Handler
-	index        int
-	urlParams    map[string]string
-	netCtx       context.Context
-	mu           sync.Mutex
+	Response          http.ResponseWriter
+	Request           *http.Request
+	handlerChain      []Handler
+	index             int
+	urlParams         map[string]string
+	netCtx            context.Context
+	mu                sync.Mutex
+	progress          chan float64
+	tracing           bool
+	checkCancellation bool
+	trace             []TraceEntry
+	body              []byte
+	errors            []error
+	mezvaro           *Mezvaro
+	resolved          map[interface{}]*resolvedInstance
+	writer            responseWriter
+	keys              map[string]interface{}
+	logAttrs          []slog.Attr
+	continueSent      bool
+	batches           map[string]*batch
 }
 
 func newContext(
 	w http.ResponseWriter, r *http.Request,
 	handlerChain []Handler, urlParams map[string]string) *Context {
+	// context of request is cancelled when client disconnects, so start
+	// from it to make handlers aware of it
+	var netCtx context.Context = context.Background()
+	if r != nil {
+		netCtx = r.Context()
+	}
 	return &Context{
 		Response:     w,
 		Request:      r,
 		index:        -1,
 		handlerChain: handlerChain,
 		urlParams:    urlParams,
-		netCtx:       context.Background(),
+		netCtx:       netCtx,
 	}
 }
 
+// NewContext creates context for provided response and request, outside of
+// any chain of handlers. Response is wrapped in the same way it is for
+// requests served by Mezvaro, so methods like Written work. It is intended
+// for testing handlers in isolation (see package mvtest) and for adapters
+// that invoke handlers directly.
+func NewContext(w http.ResponseWriter, r *http.Request) *Context {
+	c := newContext(nil, r, nil, nil)
+	c.writer = responseWriter{ResponseWriter: w}
+	c.Response = &c.writer
+	return c
+}
+
+// clone creates copy of context for executing another chain of handlers,
+// starting from the beginning. Copy shares request, response and URL
+// parameters with original and has snapshot of its context and values, but
+// changes made to copy afterwards are not visible in original and vice versa.
+// Instances resolved with Resolve are not copied, since copy can execute chain
+// of another Mezvaro instance, with its own providers.
+func (c *Context) clone() *Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clone := &Context{
+		Response:          c.Response,
+		Request:           c.Request,
+		index:             -1,
+		urlParams:         c.urlParams,
+		netCtx:            c.netCtx,
+		tracing:           c.tracing,
+		checkCancellation: c.checkCancellation,
+		body:              c.body,
+		errors:            append([]error(nil), c.errors...),
+		mezvaro:           c.mezvaro,
+		logAttrs:          append([]slog.Attr(nil), c.logAttrs...),
+		continueSent:      c.continueSent,
+	}
+	if c.keys != nil {
+		clone.keys = make(map[string]interface{}, len(c.keys))
+		for k, v := range c.keys {
+			clone.keys[k] = v
+		}
+	}
+	return clone
+}
+
 // Next invokes next handler in middleware chain. All middlewares should call
 // this or Abort method at some point of execution and Next should be called only
 // once. It is undefined what happens if Next is called more then once in same
@@ -51,6 +160,17 @@ func (c *Context) Next() {
 	c.index++
 	s := len(c.handlerChain)
 	for ; c.index < s; c.index++ {
+		if c.checkCancellation && c.Err() != nil {
+			if !c.Written() {
+				http.Error(c.Response, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			}
+			c.Abort()
+			return
+		}
+		if c.tracing {
+			c.traceHandle(c.handlerChain[c.index])
+			continue
+		}
 		c.handlerChain[c.index].Handle(c)
 	}
 }
@@ -73,6 +193,122 @@ func (c *Context) URLParam(name string) string {
 	return c.urlParams[name]
 }
 
+// LookupURLParam returns parameter from URL Path by name and boolean that
+// indicates if parameter exists. Unlike URLParam, this allows distinction
+// between missing parameter and parameter with empty value.
+func (c *Context) LookupURLParam(name string) (string, bool) {
+	value, ok := c.urlParams[name]
+	return value, ok
+}
+
+// Set stores value under provided key, for use by handlers later in chain.
+// Unlike WithValue, keys are plain strings and values can be overwritten.
+// Values stored with keys prefixed by LogKeyPrefix are also added as
+// attributes to request log written by Logger middleware.
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[string]interface{})
+	}
+	c.keys[key] = value
+	if strings.HasPrefix(key, LogKeyPrefix) {
+		c.setLogAttr(slog.Any(key[len(LogKeyPrefix):], value))
+	}
+}
+
+// Get returns value stored under provided key with Set and boolean that
+// indicates if value exists.
+func (c *Context) Get(key string) (value interface{}, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok = c.keys[key]
+	return value, ok
+}
+
+// FullPath returns path part of pattern of route that matched request, for
+// example "/users/{id}", without method and host. Pattern is set by router;
+// ServeMux from standard library sets it since Go 1.23. If request does not
+// have pattern, empty string is returned.
+func (c *Context) FullPath() string {
+	if c.Request == nil {
+		return ""
+	}
+	pattern := c.Request.Pattern
+	if i := strings.Index(pattern, "/"); i >= 0 {
+		return pattern[i:]
+	}
+	return ""
+}
+
+// RequestURI returns unmodified request target of request, for example
+// "/users/42?fields=name", as sent by client. For requests that were not
+// received by server, it is built from URL of request. If there is no
+// request, empty string is returned.
+func (c *Context) RequestURI() string {
+	if c.Request == nil {
+		return ""
+	}
+	if c.Request.RequestURI != "" {
+		return c.Request.RequestURI
+	}
+	if c.Request.URL == nil {
+		return ""
+	}
+	return c.Request.URL.RequestURI()
+}
+
+// Ctx returns snapshot of current state of context, that is not affected by
+// later changes made with methods like WithValue or WithTimeout. It should be
+// passed to functions that keep context after they return, like database
+// drivers or goroutines, instead of Context itself.
+func (c *Context) Ctx() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.netCtx
+}
+
+// SetRequest replaces request in context. Context is synchronized with
+// context of new request, so deadlines, cancellation and values of new
+// request are visible through Mezvaro context.
+func (c *Context) SetRequest(r *http.Request) {
+	c.Request = r
+	if r != nil {
+		c.mu.Lock()
+		c.netCtx = r.Context()
+		c.mu.Unlock()
+	}
+}
+
+// SetResponse replaces response writer in context. New writer is wrapped in
+// the same way original one is, so information about written response (like
+// Written method reports) and configured defaults are preserved.
+func (c *Context) SetResponse(w http.ResponseWriter) {
+	if rw, ok := w.(*responseWriter); ok {
+		c.Response = rw
+		return
+	}
+	rw := &responseWriter{ResponseWriter: w}
+	if current, ok := c.Response.(*responseWriter); ok {
+		rw.status = current.status
+		rw.written = current.written
+		rw.defaultContentType = current.defaultContentType
+		rw.noSniff = current.noSniff
+	}
+	c.Response = rw
+}
+
+// requestWithContext returns request that carries this context, so standard
+// library handlers observe deadlines, cancellation and values set through it.
+// If there is no request or context is not initialized, request is returned
+// as is.
+func (c *Context) requestWithContext() *http.Request {
+	if c.Request == nil || c.netCtx == nil {
+		return c.Request
+	}
+	return c.Request.WithContext(c.netCtx)
+}
+
 /////////////////////////////////////////////
 // net/context implementation
 /////////////////////////////////////////////
@@ -97,6 +333,22 @@ func (c *Context) Value(key interface{}) interface{} {
 	return c.netCtx.Value(key)
 }
 
+// ValueSnapshot returns values associated with provided keys, read from the
+// same state of context, so concurrent calls of WithValue can not cause
+// values from different states to be mixed. Keys without value are not
+// included in returned map. It is intended for structured logging of
+// multiple values at once.
+func (c *Context) ValueSnapshot(keys ...interface{}) map[interface{}]interface{} {
+	ctx := c.Ctx()
+	values := make(map[interface{}]interface{}, len(keys))
+	for _, key := range keys {
+		if value := ctx.Value(key); value != nil {
+			values[key] = value
+		}
+	}
+	return values
+}
+
 // WithCancel updates context's Done channel to be closed when returned cancel
 // function is called or when parent context closes channel, whichever happens
 // first.
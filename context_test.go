@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"golang.org/x/net/context"
 )
 
 func TestNewContext(t *testing.T) {
@@ -97,6 +99,77 @@ func TestAbort(t *testing.T) {
 	}
 }
 
+func TestLookupURLParam(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, map[string]string{
+		"empty": "",
+		"full":  "value",
+	})
+	if value, ok := c.LookupURLParam("empty"); !ok || value != "" {
+		t.Fatal("Empty parameter not found.")
+	}
+	if value, ok := c.LookupURLParam("full"); !ok || value != "value" {
+		t.Fatal("Parameter not found or has wrong value.")
+	}
+	if _, ok := c.LookupURLParam("missing"); ok {
+		t.Fatal("Missing parameter reported as existing.")
+	}
+}
+
+func TestContextRequestCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	request := httptest.NewRequest("GET", "/", nil).WithContext(ctx)
+	var internal, exported *Context
+	m := New(HandlerFunc(func(c *Context) {
+		internal = c
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	exported = NewContext(httptest.NewRecorder(), request)
+	if internal.Err() != nil || exported.Err() != nil {
+		t.Fatal("Context done before request is cancelled.")
+	}
+	// client disconnect cancels context of request
+	cancel()
+	if internal.Err() != context.Canceled || exported.Err() != context.Canceled {
+		t.Fatal("Context not cancelled with request: ", internal.Err(), exported.Err())
+	}
+}
+
+func TestNextCancellationCheck(t *testing.T) {
+	var laterCalled bool
+	m := New().With(WithCancellationCheck(true))
+	m.UseFunc(func(c *Context) {
+		cancel := c.WithCancel()
+		cancel()
+		c.Next()
+	}, func(c *Context) {
+		laterCalled = true
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if laterCalled {
+		t.Fatal("Handler called after context was cancelled.")
+	}
+	if response.Code != http.StatusServiceUnavailable {
+		t.Fatal("Expected status 503, got: ", response.Code)
+	}
+}
+
+func TestNextWithoutCancellationCheck(t *testing.T) {
+	var laterCalled bool
+	m := New()
+	m.UseFunc(func(c *Context) {
+		cancel := c.WithCancel()
+		cancel()
+		c.Next()
+	}, func(c *Context) {
+		laterCalled = true
+	})
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if !laterCalled {
+		t.Fatal("Handler not called without cancellation check.")
+	}
+}
+
 type netContext struct {
 	deadlineCalled bool
 	doneCalled     bool
@@ -189,3 +262,119 @@ func TestValue(t *testing.T) {
 		t.Fatal("Value extracted from context has wrong type.")
 	}
 }
+
+func TestSetGet(t *testing.T) {
+	c := newContext(nil, nil, nil, nil)
+	if _, ok := c.Get("key"); ok {
+		t.Fatal("Value found before it was set.")
+	}
+	c.Set("key", "value")
+	if value, ok := c.Get("key"); !ok || value != "value" {
+		t.Fatal("Expected value, got: ", value)
+	}
+}
+
+func TestNewContextExported(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	if c.Written() {
+		t.Fatal("New context reports written response.")
+	}
+	c.Response.Write([]byte("data"))
+	if !c.Written() || response.Body.String() != "data" {
+		t.Fatal("Response not written through context.")
+	}
+}
+
+func TestFullPathAndRequestURI(t *testing.T) {
+	var fullPath, requestURI string
+	handler := New().HF(func(c *Context) {
+		fullPath = c.FullPath()
+		requestURI = c.RequestURI()
+	})
+	request := httptest.NewRequest("GET", "/users/42?fields=name", nil)
+	// pattern is set like http.ServeMux sets it for matched request
+	request.Pattern = "GET /users/{id}"
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	if fullPath != "/users/{id}" {
+		t.Fatal("Wrong full path: ", fullPath)
+	}
+	if requestURI != "/users/42?fields=name" {
+		t.Fatal("Wrong request URI: ", requestURI)
+	}
+}
+
+func TestFullPathWithoutRequest(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if c.FullPath() != "" || c.RequestURI() != "" {
+		t.Fatal("Expected empty values without request.")
+	}
+	request, _ := http.NewRequest("GET", "/users?id=1", nil)
+	c = newContext(httptest.NewRecorder(), request, nil, nil)
+	if c.FullPath() != "" {
+		t.Fatal("Expected empty full path for request without pattern.")
+	}
+	if c.RequestURI() != "/users?id=1" {
+		t.Fatal("Request URI not built from URL: ", c.RequestURI())
+	}
+}
+
+func TestContextKey(t *testing.T) {
+	type session struct{ id string }
+	userKey := NewContextKey[string]("user")
+	otherUserKey := NewContextKey[string]("user")
+	sessionKey := NewContextKey[*session]("session")
+	var user, otherUser string
+	var found bool
+	m := New(
+		HandlerFunc(func(c *Context) { userKey.Set(c, "alice") }),
+		HandlerFunc(func(c *Context) { otherUserKey.Set(c, "bob") }),
+		HandlerFunc(func(c *Context) { sessionKey.Set(c, &session{id: "s1"}) }),
+	)
+	m.UseFunc(func(c *Context) {
+		user, _ = userKey.Get(c)
+		otherUser, _ = otherUserKey.Get(c.Ctx())
+		_, found = NewContextKey[string]("user").Get(c)
+		if s, ok := sessionKey.Get(c.requestWithContext().Context()); !ok || s.id != "s1" {
+			t.Fatal("Value not visible through request context.")
+		}
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if user != "alice" || otherUser != "bob" {
+		t.Fatal("Keys with the same name collide: ", user, otherUser)
+	}
+	if found {
+		t.Fatal("Value found under key that was not used.")
+	}
+}
+
+func TestValueSnapshot(t *testing.T) {
+	type key int
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	c.WithValue(key(1), "user")
+	c.WithValue(key(2), 42)
+	snapshot := c.ValueSnapshot(key(1), key(2), key(3))
+	if len(snapshot) != 2 || snapshot[key(1)] != "user" || snapshot[key(2)] != 42 {
+		t.Fatal("Wrong snapshot: ", snapshot)
+	}
+}
+
+func TestValueSnapshotConcurrent(t *testing.T) {
+	type key int
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.WithValue(key(i), i)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		c.ValueSnapshot(key(0), key(50))
+	}
+	<-done
+	if len(c.ValueSnapshot(key(0), key(99))) != 2 {
+		t.Fatal("Values missing from snapshot.")
+	}
+}
@@ -0,0 +1,121 @@
+package mezvaro
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+)
+
+// csrfTokenLength is number of random bytes in CSRF token.
+const csrfTokenLength = 32
+
+// CSRFConfig configures CSRF middleware. Zero values of fields are replaced
+// with defaults.
+type CSRFConfig struct {
+	// CookieName is name of cookie that holds token. Default is "_csrf".
+	CookieName string
+	// HeaderName is name of request header with submitted token. Default
+	// is "X-CSRF-Token".
+	HeaderName string
+	// FieldName is name of form field with submitted token, used if header
+	// is not present. Default is "csrf_token".
+	FieldName string
+	// CookiePath is path of token cookie. Default is "/".
+	CookiePath string
+	// Secure marks token cookie as secure.
+	Secure bool
+}
+
+// CSRF creates middleware that protects from cross-site request forgery using
+// double submit cookie pattern. Token is stored in cookie and it has to be
+// submitted in request header or form field for all requests with unsafe
+// methods, otherwise request is rejected with 403 Forbidden status and chain
+// is aborted. Requests with GET, HEAD, OPTIONS and TRACE methods are not
+// validated.
+//
+// Token for current request can be obtained with CSRFToken method, so it can
+// be rendered in forms or passed to scripts.
+func CSRF(config CSRFConfig) Handler {
+	if config.CookieName == "" {
+		config.CookieName = "_csrf"
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FieldName == "" {
+		config.FieldName = "csrf_token"
+	}
+	if config.CookiePath == "" {
+		config.CookiePath = "/"
+	}
+	return HandlerFunc(func(c *Context) {
+		var token string
+		if cookie, err := c.Request.Cookie(config.CookieName); err == nil && validCSRFToken(cookie.Value) {
+			token = cookie.Value
+		}
+
+		if !isSafeMethod(c.Request.Method) {
+			submitted := c.Request.Header.Get(config.HeaderName)
+			if submitted == "" {
+				submitted = c.Request.PostFormValue(config.FieldName)
+			}
+			if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(submitted)) != 1 {
+				http.Error(c.Response, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				c.Abort()
+				return
+			}
+		}
+
+		if token == "" {
+			var err error
+			if token, err = newCSRFToken(); err != nil {
+				http.Error(c.Response, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				c.Abort()
+				return
+			}
+			http.SetCookie(c.Response, &http.Cookie{
+				Name:     config.CookieName,
+				Value:    token,
+				Path:     config.CookiePath,
+				Secure:   config.Secure,
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+		csrfTokenKey.Set(c, token)
+		c.Next()
+	})
+}
+
+// CSRFToken returns CSRF token for current request, set by CSRF middleware.
+// If middleware is not used, empty string is returned.
+func (c *Context) CSRFToken() string {
+	token, _ := csrfTokenKey.Get(c)
+	return token
+}
+
+// newCSRFToken generates new random token.
+func newCSRFToken() (string, error) {
+	b := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validCSRFToken checks if token has format of tokens generated by newCSRFToken.
+func validCSRFToken(token string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	return err == nil && len(b) == csrfTokenLength
+}
+
+// isSafeMethod checks if HTTP method is safe, meaning it should not change
+// state on server.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func csrfMezvaro(called *bool) *Mezvaro {
+	m := New(CSRF(CSRFConfig{}))
+	m.UseFunc(func(c *Context) {
+		*called = true
+	})
+	return m
+}
+
+func csrfCookie(t *testing.T) *http.Cookie {
+	var called bool
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	cookies := response.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "_csrf" {
+		t.Fatal("CSRF cookie not set.")
+	}
+	return cookies[0]
+}
+
+func TestCSRFSafeMethod(t *testing.T) {
+	var called bool
+	var token string
+	m := New(CSRF(CSRFConfig{}))
+	m.UseFunc(func(c *Context) {
+		called = true
+		token = c.CSRFToken()
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(response, request)
+	if !called {
+		t.Fatal("Handler not called for safe method.")
+	}
+	cookies := response.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Value != token {
+		t.Fatal("Token in context does not match cookie.")
+	}
+}
+
+func TestCSRFValidHeader(t *testing.T) {
+	cookie := csrfCookie(t)
+	var called bool
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/", nil)
+	request.AddCookie(cookie)
+	request.Header.Set("X-CSRF-Token", cookie.Value)
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	if response.Code != http.StatusOK || !called {
+		t.Fatal("Request with valid token rejected.")
+	}
+	if len(response.Result().Cookies()) != 0 {
+		t.Fatal("Token regenerated for request with valid cookie.")
+	}
+}
+
+func TestCSRFValidFormField(t *testing.T) {
+	cookie := csrfCookie(t)
+	var called bool
+	response := httptest.NewRecorder()
+	form := url.Values{"csrf_token": {cookie.Value}}
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.AddCookie(cookie)
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	if response.Code != http.StatusOK || !called {
+		t.Fatal("Request with valid form token rejected.")
+	}
+}
+
+func TestCSRFMissingToken(t *testing.T) {
+	cookie := csrfCookie(t)
+	var called bool
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/", nil)
+	request.AddCookie(cookie)
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected status 403, got: ", response.Code)
+	}
+	if called {
+		t.Fatal("Handler called for request without token.")
+	}
+}
+
+func TestCSRFMismatchedToken(t *testing.T) {
+	cookie := csrfCookie(t)
+	other := csrfCookie(t)
+	var called bool
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("DELETE", "/", nil)
+	request.AddCookie(cookie)
+	request.Header.Set("X-CSRF-Token", other.Value)
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Expected status 403, got: ", response.Code)
+	}
+	if called {
+		t.Fatal("Handler called for request with mismatched token.")
+	}
+}
+
+func TestCSRFMissingCookie(t *testing.T) {
+	var called bool
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/", nil)
+	request.Header.Set("X-CSRF-Token", "forged")
+	csrfMezvaro(&called).ServeHTTP(response, request)
+	if response.Code != http.StatusForbidden || called {
+		t.Fatal("Request without cookie accepted.")
+	}
+}
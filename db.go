@@ -0,0 +1,76 @@
+package mezvaro
+
+import (
+	"database/sql"
+
+	"golang.org/x/net/context"
+)
+
+// Queryer is set of context aware query methods shared by *sql.DB, *sql.Tx
+// and *sql.Conn.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// ContextDB executes queries within request context. It is created with
+// Context.DB method.
+type ContextDB struct {
+	c  *Context
+	db Queryer
+}
+
+// DB returns wrapper around db whose query methods use request context, so
+// all queries inherit deadline, cancellation and values of request without
+// handlers having to pass context explicitly. Context is captured when query
+// is executed, so deadlines set before the query are respected.
+func (c *Context) DB(db Queryer) *ContextDB {
+	return &ContextDB{c: c, db: db}
+}
+
+// Exec executes query without returning any rows, like sql.DB.ExecContext.
+func (d *ContextDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return d.db.ExecContext(d.c.Ctx(), query, args...)
+}
+
+// Query executes query that returns rows, like sql.DB.QueryContext.
+func (d *ContextDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db.QueryContext(d.c.Ctx(), query, args...)
+}
+
+// QueryRow executes query that returns at most one row, like
+// sql.DB.QueryRowContext.
+func (d *ContextDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return d.db.QueryRowContext(d.c.Ctx(), query, args...)
+}
+
+// Transaction executes fn in transaction started on db with request context.
+// Transaction is committed if fn returns nil, and rolled back if fn returns
+// error or panics, or if context is cancelled or its deadline expires before
+// transaction is committed. Error returned by fn or context error is returned
+// in that case, otherwise error of commit is returned.
+func (c *Context) Transaction(db *sql.DB, fn func(*sql.Tx) error) error {
+	ctx := c.Ctx()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		// transaction is rolled back by database/sql too, but do not rely
+		// on it, since it happens asynchronously
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
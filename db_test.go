@@ -0,0 +1,165 @@
+package mezvaro
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// recordingQueryer records contexts queries are executed with.
+type recordingQueryer struct {
+	contexts []context.Context
+}
+
+func (q *recordingQueryer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	q.contexts = append(q.contexts, ctx)
+	return nil, nil
+}
+
+func (q *recordingQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	q.contexts = append(q.contexts, ctx)
+	return nil, nil
+}
+
+func (q *recordingQueryer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	q.contexts = append(q.contexts, ctx)
+	return nil
+}
+
+func TestDBPassesContext(t *testing.T) {
+	type key int
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	queryer := &recordingQueryer{}
+	db := c.DB(queryer)
+	cancel := c.WithTimeout(time.Minute)
+	defer cancel()
+	c.WithValue(key(1), "request")
+
+	db.Exec("UPDATE users SET active = ?", true)
+	db.Query("SELECT * FROM users")
+	db.QueryRow("SELECT * FROM users WHERE id = ?", 1)
+
+	if len(queryer.contexts) != 3 {
+		t.Fatal("Expected 3 queries, got: ", len(queryer.contexts))
+	}
+	for _, ctx := range queryer.contexts {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Fatal("Request deadline not passed to query.")
+		}
+		if ctx.Value(key(1)) != "request" {
+			t.Fatal("Request values not passed to query.")
+		}
+	}
+}
+
+func TestCtxSnapshot(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	snapshot := c.Ctx()
+	cancel := c.WithCancel()
+	cancel()
+	if snapshot.Err() != nil {
+		t.Fatal("Snapshot affected by later changes of context.")
+	}
+	if c.Ctx().Err() == nil {
+		t.Fatal("Snapshot does not reflect current state of context.")
+	}
+}
+
+// fakeConnector creates connections of fake database that only count
+// committed and rolled back transactions.
+type fakeConnector struct {
+	mu        sync.Mutex
+	commits   int
+	rollbacks int
+}
+
+func (f *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) { return fakeConn{f}, nil }
+func (f *fakeConnector) Driver() driver.Driver                            { return nil }
+
+func (f *fakeConnector) counts() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.commits, f.rollbacks
+}
+
+type fakeConn struct{ f *fakeConnector }
+
+func (c fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c fakeConn) Close() error              { return nil }
+func (c fakeConn) Begin() (driver.Tx, error) { return fakeTx{c.f}, nil }
+
+type fakeTx struct{ f *fakeConnector }
+
+func (t fakeTx) Commit() error {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.commits++
+	return nil
+}
+
+func (t fakeTx) Rollback() error {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	t.f.rollbacks++
+	return nil
+}
+
+func TestTransactionCommit(t *testing.T) {
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if err := c.Transaction(db, func(tx *sql.Tx) error { return nil }); err != nil {
+		t.Fatal("Transaction failed: ", err)
+	}
+	if commits, rollbacks := connector.counts(); commits != 1 || rollbacks != 0 {
+		t.Fatal("Transaction not committed: ", commits, rollbacks)
+	}
+}
+
+func TestTransactionRollbackOnError(t *testing.T) {
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	failure := errors.New("insert failed")
+	if err := c.Transaction(db, func(tx *sql.Tx) error { return failure }); err != failure {
+		t.Fatal("Expected error of function, got: ", err)
+	}
+	if commits, rollbacks := connector.counts(); commits != 0 || rollbacks != 1 {
+		t.Fatal("Transaction not rolled back: ", commits, rollbacks)
+	}
+}
+
+func TestTransactionRollbackOnCancel(t *testing.T) {
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithCancel()
+	err := c.Transaction(db, func(tx *sql.Tx) error {
+		cancel()
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatal("Expected cancellation error, got: ", err)
+	}
+	// database/sql might roll back transaction asynchronously
+	for i := 0; i < 100; i++ {
+		if _, rollbacks := connector.counts(); rollbacks > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if commits, rollbacks := connector.counts(); commits != 0 || rollbacks != 1 {
+		t.Fatal("Transaction not rolled back: ", commits, rollbacks)
+	}
+}
@@ -0,0 +1,79 @@
+package mezvaro
+
+import (
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// DefaultDownstreamTimeout is timeout returned by SplitDeadline when context
+// does not have deadline.
+var DefaultDownstreamTimeout = 10 * time.Second
+
+// SplitDeadline returns timeout for single call to downstream service, when
+// handler has to make n sequential calls within deadline of context. Remaining
+// time until deadline is divided equally between calls. If deadline has
+// already passed, 0 is returned. If context does not have deadline,
+// DefaultDownstreamTimeout is returned.
+func (c *Context) SplitDeadline(n int) time.Duration {
+	deadline, ok := c.Deadline()
+	if !ok {
+		return DefaultDownstreamTimeout
+	}
+	if n < 1 {
+		n = 1
+	}
+	remaining := time.Until(deadline)
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining / time.Duration(n)
+}
+
+// Sleep pauses handler for provided duration, but returns early if context is
+// cancelled or its deadline expires first. In that case context error is
+// returned, so handlers implementing backoff between retries do not keep
+// waiting for requests nobody waits for anymore.
+func (c *Context) Sleep(d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-c.Done():
+		return c.Err()
+	}
+}
+
+// Retry calls fn with context of request until it succeeds, at most attempts
+// times, and returns error of the last attempt if none succeeds. Attempts are
+// separated by backoff, which doubles after every attempt. Only idempotent
+// operations should be retried. Retrying stops early if context is cancelled,
+// in which case context error is returned, or if next attempt could not start
+// before deadline of context, in which case error of the last attempt is
+// returned. Fn is always called at least once, even if attempts is less than
+// one.
+func (c *Context) Retry(attempts int, backoff time.Duration, fn func(context.Context) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if deadline, ok := c.Deadline(); ok && time.Until(deadline) < backoff {
+				return err
+			}
+			if sleepErr := c.Sleep(backoff); sleepErr != nil {
+				return sleepErr
+			}
+			backoff *= 2
+		}
+		if err = fn(c.Ctx()); err == nil {
+			return nil
+		}
+		if ctxErr := c.Err(); ctxErr != nil {
+			return ctxErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSplitDeadline(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithTimeout(3 * time.Second)
+	defer cancel()
+	timeout := c.SplitDeadline(3)
+	if timeout > time.Second || timeout < 900*time.Millisecond {
+		t.Fatal("Remaining time not split correctly: ", timeout)
+	}
+	if c.SplitDeadline(0) < 2900*time.Millisecond {
+		t.Fatal("Whole remaining time not returned for invalid number of calls.")
+	}
+}
+
+func TestSplitDeadlineExpired(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithDeadline(time.Now().Add(-time.Second))
+	defer cancel()
+	if timeout := c.SplitDeadline(2); timeout != 0 {
+		t.Fatal("Expected 0 for expired deadline, got: ", timeout)
+	}
+}
+
+func TestSplitDeadlineWithoutDeadline(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if timeout := c.SplitDeadline(2); timeout != DefaultDownstreamTimeout {
+		t.Fatal("Expected default timeout, got: ", timeout)
+	}
+}
+
+func TestSleep(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	start := time.Now()
+	if err := c.Sleep(20 * time.Millisecond); err != nil {
+		t.Fatal("Unexpected error: ", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("Sleep returned too early.")
+	}
+}
+
+func TestSleepCancelled(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.Sleep(time.Minute); err != context.DeadlineExceeded {
+		t.Fatal("Expected deadline exceeded error, got: ", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Sleep did not return when context expired.")
+	}
+}
+
+var errUnavailable = errors.New("service unavailable")
+
+func TestRetrySuccessAfterRetry(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	calls := 0
+	start := time.Now()
+	err := c.Retry(5, 10*time.Millisecond, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errUnavailable
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatal("Expected success after 3 calls: ", calls, err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatal("Backoff not applied: ", elapsed)
+	}
+}
+
+func TestRetryExhausted(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	calls := 0
+	err := c.Retry(3, time.Millisecond, func(ctx context.Context) error {
+		calls++
+		return errUnavailable
+	})
+	if err != errUnavailable || calls != 3 {
+		t.Fatal("Expected error after 3 calls: ", calls, err)
+	}
+}
+
+func TestRetryWithoutAttempts(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	for _, attempts := range []int{0, -1} {
+		calls := 0
+		err := c.Retry(attempts, time.Millisecond, func(ctx context.Context) error {
+			calls++
+			return errUnavailable
+		})
+		if err != errUnavailable || calls != 1 {
+			t.Fatal("Expected single call for ", attempts, " attempts: ", calls, err)
+		}
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithCancel()
+	calls := 0
+	err := c.Retry(5, time.Hour, func(ctx context.Context) error {
+		calls++
+		go cancel()
+		return errUnavailable
+	})
+	if err != context.Canceled || calls != 1 {
+		t.Fatal("Retrying not stopped by cancellation: ", calls, err)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithTimeout(time.Minute)
+	defer cancel()
+	calls := 0
+	start := time.Now()
+	err := c.Retry(5, time.Hour, func(ctx context.Context) error {
+		calls++
+		return errUnavailable
+	})
+	if err != errUnavailable || calls != 1 || time.Since(start) > time.Second {
+		t.Fatal("Retrying not stopped before deadline: ", calls, err)
+	}
+}
@@ -0,0 +1,96 @@
+package mezvaro
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DeadlineWarningHeader is name of response header set by DeadlineWarning
+// middleware.
+const DeadlineWarningHeader = "X-Deadline-Warning"
+
+// DeadlineWarning returns middleware that warns clients about responses
+// completed close to deadline of request, so they can adapt timeouts or retry
+// behavior. If less than threshold fraction of time budget (time between
+// start of middleware and deadline of context) remains when response is
+// written, DeadlineWarningHeader header is set to number of milliseconds that
+// remained. Threshold 0 means default of 0.1 (10% of budget). Requests
+// without deadline are not affected.
+func DeadlineWarning(threshold float64) Handler {
+	if threshold <= 0 {
+		threshold = 0.1
+	}
+	return HandlerFunc(func(c *Context) {
+		start := time.Now()
+		original := c.Response
+		c.SetResponse(&deadlineWarningWriter{ResponseWriter: original, check: func(header http.Header) {
+			deadline, ok := c.Deadline()
+			if !ok {
+				return
+			}
+			budget := deadline.Sub(start)
+			remaining := time.Until(deadline)
+			if float64(remaining) < threshold*float64(budget) {
+				if remaining < 0 {
+					remaining = 0
+				}
+				header.Set(DeadlineWarningHeader, strconv.FormatInt(remaining.Milliseconds(), 10))
+			}
+		}})
+		defer func() {
+			c.Response = original
+		}()
+		c.Next()
+	})
+}
+
+// deadlineWarningWriter calls check function right before response is
+// committed.
+type deadlineWarningWriter struct {
+	http.ResponseWriter
+	check   func(http.Header)
+	checked bool
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *deadlineWarningWriter) WriteHeader(status int) {
+	if !informational(status) {
+		w.beforeWrite()
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *deadlineWarningWriter) Write(b []byte) (int, error) {
+	w.beforeWrite()
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface, if underlying writer supports it.
+func (w *deadlineWarningWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.beforeWrite()
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports it.
+func (w *deadlineWarningWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns underlying response writer.
+func (w *deadlineWarningWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// beforeWrite checks deadline, if it has not been checked yet.
+func (w *deadlineWarningWriter) beforeWrite() {
+	if !w.checked {
+		w.checked = true
+		w.check(w.Header())
+	}
+}
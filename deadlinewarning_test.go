@@ -0,0 +1,52 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func serveWithDeadline(timeout, work time.Duration) *httptest.ResponseRecorder {
+	m := New(HandlerFunc(func(c *Context) {
+		cancel := c.WithTimeout(timeout)
+		defer cancel()
+		c.Next()
+	}), DeadlineWarning(0.5))
+	m.UseFunc(func(c *Context) {
+		time.Sleep(work)
+		c.Response.Write([]byte("done"))
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	return response
+}
+
+func TestDeadlineWarning(t *testing.T) {
+	response := serveWithDeadline(100*time.Millisecond, 70*time.Millisecond)
+	warning := response.Header().Get(DeadlineWarningHeader)
+	remaining, err := strconv.Atoi(warning)
+	if err != nil || remaining >= 50 {
+		t.Fatal("Warning not set close to deadline: ", warning)
+	}
+}
+
+func TestDeadlineWarningEnoughTime(t *testing.T) {
+	response := serveWithDeadline(time.Minute, 0)
+	if warning := response.Header().Get(DeadlineWarningHeader); warning != "" {
+		t.Fatal("Warning set for fast response: ", warning)
+	}
+}
+
+func TestDeadlineWarningWithoutDeadline(t *testing.T) {
+	m := New(DeadlineWarning(0))
+	m.UseFunc(func(c *Context) {
+		c.Response.WriteHeader(http.StatusNoContent)
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if warning := response.Header().Get(DeadlineWarningHeader); warning != "" {
+		t.Fatal("Warning set for request without deadline: ", warning)
+	}
+}
@@ -0,0 +1,48 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// debugInfo is description of Mezvaro instance served by DebugHandler.
+type debugInfo struct {
+	Chain              []string `json:"chain"`
+	URLParamsExtractor bool     `json:"urlParamsExtractor"`
+	Debug              bool     `json:"debug"`
+}
+
+// DebugHandler returns handler that serves JSON description of instance, for
+// inspecting configuration of running services. Description contains names
+// of handlers in whole chain of instance, in order they are executed, whether
+// instance or any of its parents has URL parameters extractor and whether
+// debug mode is enabled. Description is built for every request, so it
+// reflects handlers added after DebugHandler is called. Since Mezvaro does
+// not include router, routes are not described.
+//
+// Handler exposes internals of service, so it should be mounted behind
+// authentication:
+//
+//	mux.Handle("/debug/mezvaro", admin.H(WrapHandler(m.DebugHandler())))
+func (m *Mezvaro) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chain := m.cachedWholeChain()
+		info := debugInfo{
+			Chain: make([]string, len(chain)),
+			Debug: m.isDebug(),
+		}
+		for i, h := range chain {
+			info.Chain[i] = handlerName(h)
+		}
+		for current := m; current != nil; current = current.parent {
+			if len(current.urlParamsExtractors) > 0 {
+				info.URLParamsExtractor = true
+				break
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		json.NewEncoder(w).Encode(info)
+	})
+}
@@ -0,0 +1,65 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func debugAuth(c *Context) {
+	c.Next()
+}
+
+func debugLog(c *Context) {
+	c.Next()
+}
+
+type debugHandler struct{}
+
+func (debugHandler) Handle(c *Context) {
+	c.Next()
+}
+
+func TestDebugHandler(t *testing.T) {
+	m := New(HandlerFunc(debugAuth)).With(WithDebug(true))
+	fork := m.Fork(HandlerFunc(debugLog))
+	handler := fork.DebugHandler()
+	fork.Use(debugHandler{})
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, httptest.NewRequest("GET", "/debug", nil))
+	if ct := response.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	var info struct {
+		Chain              []string `json:"chain"`
+		URLParamsExtractor bool     `json:"urlParamsExtractor"`
+		Debug              bool     `json:"debug"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &info); err != nil {
+		t.Fatal("Invalid JSON: ", response.Body.String())
+	}
+	if len(info.Chain) != 3 ||
+		!strings.HasSuffix(info.Chain[0], ".debugAuth") ||
+		!strings.HasSuffix(info.Chain[1], ".debugLog") ||
+		info.Chain[2] != "mezvaro.debugHandler" {
+		t.Fatal("Wrong chain: ", info.Chain)
+	}
+	if !info.Debug {
+		t.Fatal("Debug mode not reported.")
+	}
+	if info.URLParamsExtractor {
+		t.Fatal("Extractor reported without one.")
+	}
+}
+
+func TestDebugHandlerExtractor(t *testing.T) {
+	m := New().AddURLParamsExtractor(func(*http.Request) map[string]string { return nil })
+	response := httptest.NewRecorder()
+	m.Fork().DebugHandler().ServeHTTP(response, httptest.NewRequest("GET", "/debug", nil))
+	if !strings.Contains(response.Body.String(), `"urlParamsExtractor":true`) {
+		t.Fatal("Extractor not reported: ", response.Body.String())
+	}
+}
@@ -0,0 +1,173 @@
+package mezvaro
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"sync/atomic"
+
+	"golang.org/x/net/context"
+)
+
+// StatusMapper is function that maps error to HTTP status code of response.
+// If mapper returns 0, 500 Internal Server Error status is used.
+type StatusMapper func(error) int
+
+// defaultStatusMapper maps well known errors to their HTTP status codes.
+// Errors that implement StatusCode() int method are mapped to status they
+// report.
+func defaultStatusMapper(err error) int {
+	var statusErr interface{ StatusCode() int }
+	var maxBytesErr *http.MaxBytesError
+	var bindErr *BindError
+	switch {
+	case errors.As(err, &statusErr):
+		return statusErr.StatusCode()
+	case errors.As(err, &maxBytesErr):
+		// checked before *BindError, since binding wraps errors of reading
+		// body too
+		return http.StatusRequestEntityTooLarge
+	case errors.As(err, &bindErr):
+		return http.StatusBadRequest
+	case errors.Is(err, fs.ErrNotExist):
+		return http.StatusNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// statusMapper holds StatusMapper set with SetStatusMapper. It is read for
+// every error response, so it is stored atomically instead of guarded by lock.
+var statusMapper atomic.Value
+
+// SetStatusMapper sets function that maps errors passed to SendError to HTTP
+// status codes. It is safe to call while requests are served. Nil mapper
+// restores default mapping.
+func SetStatusMapper(mapper StatusMapper) {
+	if mapper == nil {
+		mapper = defaultStatusMapper
+	}
+	statusMapper.Store(mapper)
+}
+
+// currentStatusMapper returns StatusMapper set with SetStatusMapper, or
+// default mapper if none has been set.
+func currentStatusMapper() StatusMapper {
+	if mapper, ok := statusMapper.Load().(StatusMapper); ok {
+		return mapper
+	}
+	return defaultStatusMapper
+}
+
+// SendError responds with error, using status code determined by status mapper
+// set with SetStatusMapper, and aborts chain. If response has already been
+// written, chain is only aborted. By default errors are mapped to
+// 500 Internal Server Error, except for well known errors like *BindError
+// (400 Bad Request), *http.MaxBytesError (413 Request Entity Too Large, see
+// MaxBodySize) and fs.ErrNotExist (404 Not Found), and for errors that
+// report their status code through StatusCode() int method. Message of error
+// is written only for *BindError and errors with StatusCode() int method,
+// for other errors status text is written, so for example paths of files
+// from fs errors are not exposed to clients.
+func (c *Context) SendError(err error) {
+	status := currentStatusMapper()(err)
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	c.abortWith(status, func() error {
+		c.renderError(status, err)
+		return nil
+	})
+}
+
+// AbortWithStatus responds with provided status without body and aborts
+// chain. If response has already been written, chain is only aborted.
+func (c *Context) AbortWithStatus(status int) {
+	c.abortWith(status, nil)
+}
+
+// AbortWithError records error with Error method, responds with provided
+// status and error message and aborts chain. Like in SendError, error
+// message is written only for client errors that are meant for clients,
+// otherwise status text is written, since error message might contain
+// internal details. If response has already been written, error is recorded
+// and chain is only aborted.
+func (c *Context) AbortWithError(status int, err error) {
+	c.Error(err)
+	c.abortWith(status, func() error {
+		c.renderError(status, err)
+		return nil
+	})
+}
+
+// AbortWithJSON responds with provided status and v encoded as JSON and
+// aborts chain. If response has already been written, chain is only aborted. Encoding errors are handled same as in JSON method, and chain
+// is aborted regardless of them.
+func (c *Context) AbortWithJSON(status int, v interface{}) error {
+	return c.abortWith(status, func() error {
+		return c.JSON(status, v)
+	})
+}
+
+// AbortWithStatusJSON is alias for AbortWithJSON.
+func (c *Context) AbortWithStatusJSON(status int, v interface{}) error {
+	return c.AbortWithJSON(status, v)
+}
+
+// abortWith is common implementation of helpers that respond and abort chain.
+// Response is written with provided function, or if it is nil, only status
+// is written. If response has already been written, nothing is written, so
+// error response is not appended to partial one. Chain is aborted even if
+// writing fails.
+func (c *Context) abortWith(status int, write func() error) error {
+	defer c.Abort()
+	if c.Written() {
+		return nil
+	}
+	if write == nil {
+		c.Response.WriteHeader(status)
+		return nil
+	}
+	return write()
+}
+
+// Error records error that occurred during processing of request, so it can
+// be inspected later, for example by logging middleware.
+func (c *Context) Error(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.errors = append(c.errors, err)
+}
+
+// Errors returns errors recorded with Error method, in order they are recorded.
+func (c *Context) Errors() []error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	errs := make([]error, len(c.errors))
+	copy(errs, c.errors)
+	return errs
+}
+
+// renderError writes response with provided status and error message. Error
+// message is written only for client errors whose messages are meant for
+// clients (see publicError), otherwise status text is written, since error
+// message might contain internal details, like paths of files.
+func (c *Context) renderError(status int, err error) {
+	message := http.StatusText(status)
+	if status < http.StatusInternalServerError && publicError(err) {
+		message = err.Error()
+	}
+	http.Error(c.Response, message, status)
+}
+
+// publicError reports whether message of error is safe to show to clients.
+// Those are *BindError and errors that report their status code through
+// StatusCode() int method.
+func publicError(err error) bool {
+	var statusErr interface{ StatusCode() int }
+	var bindErr *BindError
+	return errors.As(err, &statusErr) || errors.As(err, &bindErr)
+}
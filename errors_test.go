@@ -0,0 +1,203 @@
+package mezvaro
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type notFoundError struct {
+	resource string
+}
+
+func (e notFoundError) Error() string {
+	return e.resource + " not found"
+}
+
+type teapotError struct{}
+
+func (teapotError) Error() string   { return "teapot" }
+func (teapotError) StatusCode() int { return http.StatusTeapot }
+
+func sendErrorResponse(err error) (*httptest.ResponseRecorder, *Context) {
+	response := httptest.NewRecorder()
+	c := newContext(response, nil, nil, nil)
+	c.SendError(err)
+	return response, c
+}
+
+func TestSendErrorDefault(t *testing.T) {
+	response, c := sendErrorResponse(errors.New("database password is secret"))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Expected status 500, got: ", response.Code)
+	}
+	if strings.Contains(response.Body.String(), "secret") {
+		t.Fatal("Internal error message leaked to response.")
+	}
+	if !c.IsAborted() {
+		t.Fatal("Context not aborted.")
+	}
+}
+
+func TestSendErrorKnownErrors(t *testing.T) {
+	cases := map[error]int{
+		teapotError{}:                            http.StatusTeapot,
+		&BindError{Format: "json"}:               http.StatusBadRequest,
+		fmt.Errorf("open: %w", fs.ErrNotExist):   http.StatusNotFound,
+		fmt.Errorf("wrapped: %w", teapotError{}): http.StatusTeapot,
+		fmt.Errorf("open: %w", fs.ErrPermission): http.StatusForbidden,
+	}
+	for err, status := range cases {
+		response, _ := sendErrorResponse(err)
+		if response.Code != status {
+			t.Fatalf("Expected status %d for %v, got: %d", status, err, response.Code)
+		}
+	}
+}
+
+func TestSendErrorHidesFileErrors(t *testing.T) {
+	_, err := os.Open("/srv/secret/config.yaml")
+	response, _ := sendErrorResponse(err)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected status 404, got: ", response.Code)
+	}
+	if strings.Contains(response.Body.String(), "secret") {
+		t.Fatal("Path of file exposed to client: ", response.Body.String())
+	}
+	response, _ = sendErrorResponse(&BindError{Format: "json", Message: "invalid JSON", Err: errors.New("syntax")})
+	if response.Code != http.StatusBadRequest || response.Body.String() == "Bad Request\n" {
+		t.Fatal("Message of bind error not shown to client: ", response.Body.String())
+	}
+}
+
+func TestSendErrorCustomMapper(t *testing.T) {
+	SetStatusMapper(func(err error) int {
+		var notFound notFoundError
+		if errors.As(err, &notFound) {
+			return http.StatusNotFound
+		}
+		return 0
+	})
+	defer SetStatusMapper(defaultStatusMapper)
+
+	response, _ := sendErrorResponse(notFoundError{resource: "user"})
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected status 404, got: ", response.Code)
+	}
+	// message of error that does not opt in is not shown to client
+	if strings.TrimSpace(response.Body.String()) != "Not Found" {
+		t.Fatal("Wrong error message: ", response.Body.String())
+	}
+	response, _ = sendErrorResponse(errors.New("unknown"))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Expected status 500 for unmapped error, got: ", response.Code)
+	}
+}
+
+func TestSetStatusMapperConcurrent(t *testing.T) {
+	defer SetStatusMapper(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			SetStatusMapper(func(error) int { return http.StatusTeapot })
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		sendErrorResponse(errors.New("error"))
+	}
+	<-done
+	response, _ := sendErrorResponse(errors.New("error"))
+	if response.Code != http.StatusTeapot {
+		t.Fatal("Mapper not replaced: ", response.Code)
+	}
+	SetStatusMapper(nil)
+	response, _ = sendErrorResponse(errors.New("error"))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Default mapper not restored: ", response.Code)
+	}
+}
+
+func TestAbortWithHelpers(t *testing.T) {
+	tests := []struct {
+		name   string
+		abort  func(c *Context)
+		status int
+		body   string
+	}{
+		{"AbortWithStatus", func(c *Context) { c.AbortWithStatus(http.StatusNoContent) }, http.StatusNoContent, ""},
+		{"AbortWithError", func(c *Context) { c.AbortWithError(http.StatusConflict, errors.New("exists")) }, http.StatusConflict, "Conflict\n"},
+		{"AbortWithError", func(c *Context) { c.AbortWithError(http.StatusConflict, teapotError{}) }, http.StatusConflict, "teapot\n"},
+		{"AbortWithJSON", func(c *Context) { c.AbortWithJSON(http.StatusForbidden, map[string]string{"error": "denied"}) }, http.StatusForbidden, "{\"error\":\"denied\"}\n"},
+		{"AbortWithStatusJSON", func(c *Context) { c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "denied"}) }, http.StatusForbidden, "{\"error\":\"denied\"}\n"},
+	}
+	for _, test := range tests {
+		var nextCalled bool
+		response := httptest.NewRecorder()
+		m := New(HandlerFunc(test.abort), HandlerFunc(func(c *Context) {
+			nextCalled = true
+		}))
+		m.ServeHTTP(response, nil)
+		if response.Code != test.status {
+			t.Fatal(test.name, " wrote wrong status: ", response.Code)
+		}
+		if response.Body.String() != test.body {
+			t.Fatal(test.name, " wrote wrong body: ", response.Body.String())
+		}
+		if nextCalled {
+			t.Fatal(test.name, " did not abort chain.")
+		}
+	}
+}
+
+func TestAbortWithHelpersAfterPartialWrite(t *testing.T) {
+	tests := map[string]func(c *Context){
+		"SendError":           func(c *Context) { c.SendError(errors.New("failed")) },
+		"AbortWithStatus":     func(c *Context) { c.AbortWithStatus(http.StatusConflict) },
+		"AbortWithError":      func(c *Context) { c.AbortWithError(http.StatusInternalServerError, errors.New("failed")) },
+		"AbortWithJSON":       func(c *Context) { c.AbortWithJSON(http.StatusForbidden, map[string]string{"error": "denied"}) },
+		"AbortWithStatusJSON": func(c *Context) { c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "denied"}) },
+	}
+	for name, abort := range tests {
+		var nextCalled bool
+		response := httptest.NewRecorder()
+		m := New(HandlerFunc(func(c *Context) {
+			c.Response.Write([]byte("partial"))
+			abort(c)
+		}), HandlerFunc(func(c *Context) {
+			nextCalled = true
+		}))
+		m.ServeHTTP(response, nil)
+		if response.Code != http.StatusOK || response.Body.String() != "partial" {
+			t.Fatal(name, " wrote after partial response: ", response.Code, response.Body.String())
+		}
+		if nextCalled {
+			t.Fatal(name, " did not abort chain.")
+		}
+	}
+}
+
+func TestAbortWithErrorRecordsError(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	failure := errors.New("database unavailable")
+	c.AbortWithError(http.StatusServiceUnavailable, failure)
+	if errs := c.Errors(); len(errs) != 1 || errs[0] != failure {
+		t.Fatal("Error not recorded: ", errs)
+	}
+}
+
+func TestAbortWithJSONFailure(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := newContext(response, nil, nil, nil)
+	if err := c.AbortWithJSON(http.StatusOK, make(chan int)); err == nil {
+		t.Fatal("Expected encoding error.")
+	}
+	if response.Code != http.StatusInternalServerError || !c.IsAborted() {
+		t.Fatal("Encoding failure not handled uniformly: ", response.Code)
+	}
+}
@@ -8,9 +8,7 @@ import (
 	"github.com/mssola/user_agent"
 )
 
-type userInfo int
-
-const browserInfoKey userInfo = 1
+var browserInfoKey = mv.NewContextKey[BrowserInfo]("browser info")
 
 type UserAgentExtractor struct {
 	verbose bool
@@ -57,12 +55,12 @@ func (uae UserAgentExtractor) Handle(c *mv.Context) {
 		Platform:      ua.Platform(),
 		Mobile:        ua.Mobile(),
 	}
-	c.WithValue(browserInfoKey, browserInfo)
+	browserInfoKey.Set(c, browserInfo)
 	c.Next()
 }
 
 func BrowserInfoHandler(c *mv.Context) {
-	browserInfo := c.Value(browserInfoKey).(BrowserInfo)
+	browserInfo, _ := browserInfoKey.Get(c)
 	c.Response.Write([]byte(browserInfo.String()))
 }
 
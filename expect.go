@@ -0,0 +1,36 @@
+package mezvaro
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ExpectsContinue reports whether client sent request with "Expect:
+// 100-continue" header, so it waits for 100 Continue response before sending
+// request body.
+func (c *Context) ExpectsContinue() bool {
+	return c.Request != nil && strings.EqualFold(c.Request.Header.Get("Expect"), "100-continue")
+}
+
+// SendContinue sends 100 Continue response to client that waits for it
+// before sending request body (see ExpectsContinue). It does nothing for
+// other requests, if it has already been sent or if response has already been
+// written.
+//
+// Standard library sends 100 Continue automatically when handler starts
+// reading body, so SendContinue is not required. It allows middlewares, like
+// authentication, to inspect request and let client proceed with upload
+// explicitly, or to reject it with final response before client sends
+// potentially large body. It should be called before body is read.
+func (c *Context) SendContinue() {
+	if !c.ExpectsContinue() || c.Written() {
+		return
+	}
+	c.mu.Lock()
+	sent := c.continueSent
+	c.continueSent = true
+	c.mu.Unlock()
+	if !sent {
+		c.Response.WriteHeader(http.StatusContinue)
+	}
+}
@@ -0,0 +1,93 @@
+package mezvaro
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func expectContinueServer() *httptest.Server {
+	m := New(HandlerFunc(func(c *Context) {
+		if c.Request.Header.Get("Authorization") != "secret" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.SendContinue()
+		c.Next()
+	}))
+	m.UseFunc(func(c *Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Response.Write(body)
+	})
+	return httptest.NewServer(m)
+}
+
+func sendExpectContinue(t *testing.T, server *httptest.Server, authorization string) (*http.Response, *bufio.Reader, net.Conn) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "POST / HTTP/1.1\r\nHost: example.com\r\nContent-Length: 5\r\n"+
+		"Expect: 100-continue\r\nAuthorization: "+authorization+"\r\n\r\n")
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal("Reading response failed: ", err)
+	}
+	return response, reader, conn
+}
+
+func TestSendContinue(t *testing.T) {
+	server := expectContinueServer()
+	defer server.Close()
+	response, reader, conn := sendExpectContinue(t, server, "secret")
+	defer conn.Close()
+	if response.StatusCode != http.StatusContinue {
+		t.Fatal("Expected 100 Continue, got: ", response.StatusCode)
+	}
+	io.WriteString(conn, "hello")
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal("Reading final response failed: ", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK || string(body) != "hello" {
+		t.Fatal("Wrong final response: ", response.StatusCode, string(body))
+	}
+}
+
+func TestSendContinueRejected(t *testing.T) {
+	server := expectContinueServer()
+	defer server.Close()
+	response, _, conn := sendExpectContinue(t, server, "wrong")
+	defer conn.Close()
+	if response.StatusCode != http.StatusUnauthorized {
+		t.Fatal("Expected rejection before body is sent, got: ", response.StatusCode)
+	}
+}
+
+func TestSendContinueWithoutExpect(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", nil)
+	response := httptest.NewRecorder()
+	c := NewContext(response, request)
+	if c.ExpectsContinue() {
+		t.Fatal("Request without Expect header expects continue.")
+	}
+	c.SendContinue()
+	if c.Written() || response.Code != http.StatusOK || response.Flushed {
+		t.Fatal("Continue sent for request without Expect header.")
+	}
+}
+
+func TestInformationalStatusDoesNotCommit(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/", nil)
+	request.Header.Set("Expect", "100-continue")
+	c := NewContext(httptest.NewRecorder(), request)
+	c.SendContinue()
+	if c.Written() {
+		t.Fatal("Informational status committed response.")
+	}
+}
@@ -0,0 +1,118 @@
+package mezvaro
+
+import (
+	"errors"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Group is collection of goroutines working on subtasks of single request.
+// It is minimal equivalent of errgroup.Group from golang.org/x/sync, tied to
+// request context.
+type Group struct {
+	c       *Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+	mu      sync.Mutex
+	errs    []error
+	record  bool
+}
+
+// Group creates new group of goroutines together with context derived from
+// request context. Returned context is cancelled when request context is
+// done (for example when its timeout expires), when any of goroutines in
+// group returns error, or when Wait returns, whichever happens first.
+// Subtasks should use it for cancellation.
+func (c *Context) Group() (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(c.Ctx())
+	return &Group{c: c, cancel: cancel}, ctx
+}
+
+// RecordErrors makes group record every error returned by its functions
+// with Error method of request context, so they are visible to middlewares
+// like logger. It has to be called before functions are started with Go.
+func (g *Group) RecordErrors() *Group {
+	g.record = true
+	return g
+}
+
+// Go runs provided function in new goroutine. First error returned by any of
+// functions cancels context of group and is returned by Wait.
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.mu.Lock()
+			g.errs = append(g.errs, err)
+			g.mu.Unlock()
+			if g.record {
+				g.c.Error(err)
+			}
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until all functions started with Go return, and returns first
+// error returned by any of them, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+// Errors returns all errors returned by functions of group, in order they
+// are returned. It should be called after Wait.
+func (g *Group) Errors() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]error(nil), g.errs...)
+}
+
+// Race runs provided functions concurrently with context derived from
+// request context and returns result of the first one that succeeds, for
+// example for hedged requests to redundant backends. Context passed to
+// functions is cancelled as soon as result is available, so remaining
+// functions can stop. If all functions fail, their errors are joined and
+// returned. If request context is done first, its error is returned without
+// waiting for functions.
+func (c *Context) Race(fns ...func(context.Context) (interface{}, error)) (interface{}, error) {
+	if len(fns) == 0 {
+		return nil, errors.New("mezvaro: no functions to race")
+	}
+	ctx, cancel := context.WithCancel(c.Ctx())
+	defer cancel()
+	type result struct {
+		value interface{}
+		err   error
+	}
+	// buffered, so functions that finish late do not block forever
+	results := make(chan result, len(fns))
+	for _, fn := range fns {
+		fn := fn
+		go func() {
+			value, err := fn(ctx)
+			results <- result{value: value, err: err}
+		}()
+	}
+	errs := make([]error, 0, len(fns))
+	for range fns {
+		select {
+		case r := <-results:
+			if r.err == nil {
+				return r.value, nil
+			}
+			errs = append(errs, r.err)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, errors.Join(errs...)
+}
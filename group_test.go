@@ -0,0 +1,165 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestGroup(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	g, _ := c.Group()
+	results := make([]int, 5)
+	for i := range results {
+		i := i
+		g.Go(func() error {
+			results[i] = i * i
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal("Unexpected error: ", err)
+	}
+	for i, r := range results {
+		if r != i*i {
+			t.Fatal("Task not executed: ", results)
+		}
+	}
+}
+
+func TestGroupFailureCancelsOthers(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	g, ctx := c.Group()
+	failure := errors.New("task failed")
+	cancelled := make(chan bool, 3)
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			select {
+			case <-ctx.Done():
+				cancelled <- true
+			case <-time.After(5 * time.Second):
+				cancelled <- false
+			}
+			return ctx.Err()
+		})
+	}
+	g.Go(func() error {
+		return failure
+	})
+	if err := g.Wait(); err != failure {
+		t.Fatal("Expected error of failed task, got: ", err)
+	}
+	close(cancelled)
+	for c := range cancelled {
+		if !c {
+			t.Fatal("Task not cancelled after failure of other task.")
+		}
+	}
+}
+
+func TestGroupRequestCancellation(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithCancel()
+	g, ctx := c.Group()
+	g.Go(func() error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	cancel()
+	if err := g.Wait(); err == nil {
+		t.Fatal("Group context not cancelled with request context.")
+	}
+}
+
+func TestGroupRequestTimeout(t *testing.T) {
+	var groupErr error
+	var recorded, collected []error
+	m := New(HandlerFunc(func(c *Context) {
+		cancel := c.WithTimeout(20 * time.Millisecond)
+		defer cancel()
+		c.Next()
+	}))
+	m.UseFunc(func(c *Context) {
+		g, ctx := c.Group()
+		g.RecordErrors()
+		for i := 0; i < 3; i++ {
+			g.Go(func() error {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(5 * time.Second):
+					return nil
+				}
+			})
+		}
+		groupErr = g.Wait()
+		collected = g.Errors()
+		recorded = c.Errors()
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if groupErr != context.DeadlineExceeded {
+		t.Fatal("Tasks not cancelled by request timeout: ", groupErr)
+	}
+	if len(collected) != 3 || len(recorded) != 3 {
+		t.Fatal("Errors of tasks not collected: ", collected, recorded)
+	}
+}
+
+func TestRaceFirstSuccess(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancelled := make(chan bool, 1)
+	value, err := c.Race(
+		func(ctx context.Context) (interface{}, error) {
+			return nil, errors.New("backend down")
+		},
+		func(ctx context.Context) (interface{}, error) {
+			select {
+			case <-ctx.Done():
+				cancelled <- true
+			case <-time.After(5 * time.Second):
+				cancelled <- false
+			}
+			return "slow", nil
+		},
+		func(ctx context.Context) (interface{}, error) {
+			time.Sleep(10 * time.Millisecond)
+			return "fast", nil
+		},
+	)
+	if err != nil || value != "fast" {
+		t.Fatal("Wrong result of race: ", value, err)
+	}
+	if !<-cancelled {
+		t.Fatal("Slow function not cancelled.")
+	}
+}
+
+func TestRaceAllFail(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	first, second := errors.New("first"), errors.New("second")
+	_, err := c.Race(
+		func(ctx context.Context) (interface{}, error) { return nil, first },
+		func(ctx context.Context) (interface{}, error) { return nil, second },
+	)
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatal("Errors of functions not returned: ", err)
+	}
+}
+
+func TestRaceDeadline(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := c.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	_, err := c.Race(func(ctx context.Context) (interface{}, error) {
+		time.Sleep(time.Second)
+		return "late", nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatal("Race did not respect deadline: ", err)
+	}
+}
@@ -0,0 +1,73 @@
+package mezvaro
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// NormalizeHost creates middleware that redirects requests with Host header
+// different from canonical host (for example "www.example.com" instead of
+// "example.com") to the same path and query on canonical host, using 301
+// Moved Permanently status. Chain is aborted after redirect. Hosts are
+// compared case insensitively.
+func NormalizeHost(canonical string) Handler {
+	return HandlerFunc(func(c *Context) {
+		if strings.EqualFold(c.Request.Host, canonical) {
+			c.Next()
+			return
+		}
+		scheme := "http"
+		if c.Request.TLS != nil {
+			scheme = "https"
+		}
+		http.Redirect(c.Response, c.Request, scheme+"://"+canonical+c.Request.URL.RequestURI(), http.StatusMovedPermanently)
+		c.Abort()
+	})
+}
+
+// CanonicalHeaders creates middleware that normalizes request headers before
+// they reach other handlers. Leading and trailing white space is trimmed from
+// all header values, and Host is lowercased and stripped of trailing dot.
+func CanonicalHeaders() Handler {
+	return HandlerFunc(func(c *Context) {
+		for _, values := range c.Request.Header {
+			for i, value := range values {
+				values[i] = strings.TrimSpace(value)
+			}
+		}
+		c.Request.Host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(c.Request.Host)), ".")
+		c.Next()
+	})
+}
+
+// RemoteIP returns IP address of direct peer that sent request, parsed from
+// RemoteAddr of request. Brackets around IPv6 addresses are removed. Proxy
+// headers are not consulted, so behind proxy this is address of the proxy.
+// If there is no request, empty string is returned.
+func (c *Context) RemoteIP() string {
+	host, _ := c.remoteHostPort()
+	return host
+}
+
+// RemotePort returns port of direct peer that sent request, parsed from
+// RemoteAddr of request. If address does not contain port, empty string is
+// returned.
+func (c *Context) RemotePort() string {
+	_, port := c.remoteHostPort()
+	return port
+}
+
+// remoteHostPort splits RemoteAddr of request to host and port.
+func (c *Context) remoteHostPort() (host, port string) {
+	if c.Request == nil {
+		return "", ""
+	}
+	addr := c.Request.RemoteAddr
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// address without port
+		return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]"), ""
+	}
+	return host, port
+}
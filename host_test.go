@@ -0,0 +1,84 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNormalizeHostRedirect(t *testing.T) {
+	var called bool
+	m := New(NormalizeHost("example.com"))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "http://www.example.com/path?q=1", nil)
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusMovedPermanently {
+		t.Fatal("Expected status 301, got: ", response.Code)
+	}
+	if location := response.Header().Get("Location"); location != "http://example.com/path?q=1" {
+		t.Fatal("Wrong redirect location: ", location)
+	}
+	if called {
+		t.Fatal("Handler called after redirect.")
+	}
+}
+
+func TestNormalizeHostCanonical(t *testing.T) {
+	var called bool
+	m := New(NormalizeHost("example.com"))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "http://Example.com/path", nil)
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected status 200, got: ", response.Code)
+	}
+	if !called {
+		t.Fatal("Handler not called for canonical host.")
+	}
+}
+
+func TestCanonicalHeaders(t *testing.T) {
+	var host, header string
+	m := New(CanonicalHeaders())
+	m.UseFunc(func(c *Context) {
+		host = c.Request.Host
+		header = c.Request.Header.Get("X-Custom")
+	})
+	request, _ := http.NewRequest("GET", "http://Example.COM./", nil)
+	request.Header.Set("X-Custom", "  value\t")
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if host != "example.com" {
+		t.Fatal("Host not normalized: ", host)
+	}
+	if header != "value" {
+		t.Fatal("Header value not trimmed: ", header)
+	}
+}
+
+func TestRemoteIPAndPort(t *testing.T) {
+	tests := []struct {
+		addr, ip, port string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1", "1234"},
+		{"[2001:db8::1]:443", "2001:db8::1", "443"},
+		{"[2001:db8::1]", "2001:db8::1", ""},
+		{"192.0.2.1", "192.0.2.1", ""},
+	}
+	for _, test := range tests {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.RemoteAddr = test.addr
+		c := newContext(httptest.NewRecorder(), request, nil, nil)
+		if ip := c.RemoteIP(); ip != test.ip {
+			t.Fatal("Wrong IP for ", test.addr, ": ", ip)
+		}
+		if port := c.RemotePort(); port != test.port {
+			t.Fatal("Wrong port for ", test.addr, ": ", port)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package mezvaro
+
+import (
+	"fmt"
+	"time"
+)
+
+// KeepAlive calls ping every interval, until context is done or ping returns
+// error, which is returned. It is intended for handlers that hijack
+// connection for WebSocket, where ping should send ping frame with WebSocket
+// library in use (and its pong handler extends read deadline), so connections
+// of unresponsive clients are detected. Loop is tied to context, so it exits
+// when request is cancelled or server shuts down, in which case context error
+// is returned. KeepAlive blocks, so it is usually run in separate goroutine
+// next to loop that reads messages. Error is returned right away if interval
+// is not positive.
+func (c *Context) KeepAlive(interval time.Duration, ping func() error) error {
+	if interval <= 0 {
+		return fmt.Errorf("mezvaro: invalid keep alive interval %v", interval)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		case <-ticker.C:
+			if err := c.Err(); err != nil {
+				// both channels might be ready, do not ping after
+				// cancellation
+				return err
+			}
+			if err := ping(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestKeepAlive(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	cancel := c.WithCancel()
+	pings := 0
+	err := c.KeepAlive(5*time.Millisecond, func() error {
+		pings++
+		if pings == 2 {
+			cancel()
+		}
+		if pings > 2 {
+			t.Fatal("Loop did not exit after cancellation.")
+		}
+		return nil
+	})
+	if err != context.Canceled {
+		t.Fatal("Expected cancellation error, got: ", err)
+	}
+	if pings != 2 {
+		t.Fatal("Wrong number of pings: ", pings)
+	}
+}
+
+func TestKeepAlivePingFailure(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	failure := errors.New("connection closed")
+	err := c.KeepAlive(time.Millisecond, func() error {
+		return failure
+	})
+	if err != failure {
+		t.Fatal("Expected ping error, got: ", err)
+	}
+}
+
+func TestKeepAliveInvalidInterval(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	for _, interval := range []time.Duration{0, -time.Second} {
+		err := c.KeepAlive(interval, func() error {
+			t.Fatal("Ping called with invalid interval.")
+			return nil
+		})
+		if err == nil {
+			t.Fatal("Invalid interval accepted: ", interval)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package mezvaro
+
+import (
+	"log/slog"
+	"time"
+)
+
+// LogKeyPrefix is prefix of keys (see Set) whose values are added to request
+// log written by Logger middleware. Prefix is stripped from attribute name,
+// so value set under key "log.user_id" is logged as "user_id".
+const LogKeyPrefix = "log."
+
+// Logger returns middleware that writes single record per request to provided
+// logger, after rest of the chain finishes. Record contains method, path,
+// status and duration of request, trace and span IDs if request carries
+// traceparent header (see TraceContext), followed by values that handlers
+// stored with keys prefixed by LogKeyPrefix. If logger is nil, slog.Default
+// is used.
+func Logger(logger *slog.Logger) Handler {
+	return HandlerFunc(func(c *Context) {
+		log := logger
+		if log == nil {
+			log = slog.Default()
+		}
+		start := time.Now()
+		c.Next()
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.responseStatus()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if traceID, spanID, ok := c.TraceContext(); ok {
+			attrs = append(attrs, slog.String("trace_id", traceID), slog.String("span_id", spanID))
+		}
+		c.mu.Lock()
+		attrs = append(attrs, c.logAttrs...)
+		c.mu.Unlock()
+		log.LogAttrs(c, slog.LevelInfo, "request", attrs...)
+	})
+}
+
+// setLogAttr adds attribute to request log, replacing previously set
+// attribute with the same name. Caller has to hold context lock.
+func (c *Context) setLogAttr(attr slog.Attr) {
+	for i := range c.logAttrs {
+		if c.logAttrs[i].Key == attr.Key {
+			c.logAttrs[i] = attr
+			return
+		}
+	}
+	c.logAttrs = append(c.logAttrs, attr)
+}
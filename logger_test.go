@@ -0,0 +1,51 @@
+package mezvaro
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerIncludesLogValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	m := New(Logger(logger))
+	m.UseFunc(func(c *Context) {
+		c.Set("log.user_id", 42)
+		c.Set("session", "secret")
+		c.Next()
+	}, func(c *Context) {
+		c.Response.WriteHeader(http.StatusCreated)
+	})
+	request, _ := http.NewRequest("POST", "/users", nil)
+	request.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	m.ServeHTTP(httptest.NewRecorder(), request)
+
+	record := buf.String()
+	for _, expected := range []string{"method=POST", "path=/users", "status=201", "user_id=42", "trace_id=4bf92f3577b34da6a3ce929d0e0e4736"} {
+		if !strings.Contains(record, expected) {
+			t.Fatal("Log record missing ", expected, ": ", record)
+		}
+	}
+	if strings.Contains(record, "secret") {
+		t.Fatal("Value without log prefix logged: ", record)
+	}
+}
+
+func TestLoggerOverwrittenValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	m := New(Logger(logger))
+	m.UseFunc(func(c *Context) {
+		c.Set("log.user_id", 1)
+		c.Set("log.user_id", 2)
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Count(buf.String(), "user_id=") != 1 || !strings.Contains(buf.String(), "user_id=2") {
+		t.Fatal("Overwritten value not replaced in log record: ", buf.String())
+	}
+}
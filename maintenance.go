@@ -0,0 +1,36 @@
+package mezvaro
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// MaintenanceMode returns middleware that, while enabled returns true, responds
+// to requests with 503 Service Unavailable status and Retry-After header set
+// to retryAfter (rounded to seconds) and aborts chain. Requests for allowed
+// paths, like health checks, are passed through even in maintenance mode.
+// Since enabled is called for every request, maintenance mode can be toggled
+// at runtime.
+func MaintenanceMode(enabled func() bool, retryAfter time.Duration, allowedPaths ...string) Handler {
+	allowed := make(map[string]struct{}, len(allowedPaths))
+	for _, p := range allowedPaths {
+		allowed[p] = struct{}{}
+	}
+	seconds := strconv.Itoa(int((retryAfter + time.Second - 1) / time.Second))
+	return HandlerFunc(func(c *Context) {
+		if !enabled() {
+			c.Next()
+			return
+		}
+		if c.Request != nil {
+			if _, ok := allowed[c.Request.URL.Path]; ok {
+				c.Next()
+				return
+			}
+		}
+		c.Response.Header().Set("Retry-After", seconds)
+		http.Error(c.Response, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		c.Abort()
+	})
+}
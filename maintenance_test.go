@@ -0,0 +1,42 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	var enabled atomic.Bool
+	var called bool
+	m := New(MaintenanceMode(enabled.Load, 90*time.Second, "/health"))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	serve := func(path string) *httptest.ResponseRecorder {
+		called = false
+		request, _ := http.NewRequest("GET", path, nil)
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		return response
+	}
+
+	if response := serve("/users"); response.Code != http.StatusOK || !called {
+		t.Fatal("Request not passed through when maintenance mode is disabled.")
+	}
+
+	enabled.Store(true)
+	response := serve("/users")
+	if response.Code != http.StatusServiceUnavailable || called {
+		t.Fatal("Request not rejected in maintenance mode, status: ", response.Code)
+	}
+	if retryAfter := response.Header().Get("Retry-After"); retryAfter != "90" {
+		t.Fatal("Wrong Retry-After header: ", retryAfter)
+	}
+
+	if response := serve("/health"); response.Code != http.StatusOK || !called {
+		t.Fatal("Allowed path rejected in maintenance mode.")
+	}
+}
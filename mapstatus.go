@@ -0,0 +1,70 @@
+package mezvaro
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// MapStatus returns middleware that rewrites status codes of responses
+// written by rest of the chain according to mapping, before they are
+// committed. For example, gateway can respond with 403 Forbidden instead of
+// 401 Unauthorized returned by upstream service. Only status is changed,
+// headers and body are written as they are. Statuses without entry in
+// mapping are not changed.
+func MapStatus(mapping map[int]int) Handler {
+	return HandlerFunc(func(c *Context) {
+		original := c.Response
+		c.SetResponse(&mapStatusWriter{ResponseWriter: original, mapping: mapping})
+		defer func() {
+			c.Response = original
+		}()
+		c.Next()
+	})
+}
+
+// mapStatusWriter rewrites status of response according to mapping.
+type mapStatusWriter struct {
+	http.ResponseWriter
+	mapping map[int]int
+	written bool
+}
+
+// WriteHeader implements http.ResponseWriter interface.
+func (w *mapStatusWriter) WriteHeader(status int) {
+	if mapped, ok := w.mapping[status]; ok {
+		status = mapped
+	}
+	if !informational(status) {
+		w.written = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *mapStatusWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher interface, if underlying writer supports it.
+func (w *mapStatusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.written {
+			w.WriteHeader(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports it.
+func (w *mapStatusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns underlying response writer.
+func (w *mapStatusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,46 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMapStatus(t *testing.T) {
+	m := New(MapStatus(map[int]int{http.StatusUnauthorized: http.StatusForbidden}))
+	m.UseFunc(func(c *Context) {
+		http.Error(c.Response, "token expired", http.StatusUnauthorized)
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusForbidden {
+		t.Fatal("Status not mapped: ", response.Code)
+	}
+	if response.Body.String() != "token expired\n" {
+		t.Fatal("Body changed: ", response.Body.String())
+	}
+}
+
+func TestMapStatusUnmapped(t *testing.T) {
+	m := New(MapStatus(map[int]int{http.StatusUnauthorized: http.StatusForbidden}))
+	m.UseFunc(func(c *Context) {
+		http.Error(c.Response, "missing", http.StatusNotFound)
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusNotFound || response.Body.String() != "missing\n" {
+		t.Fatal("Unmapped status changed: ", response.Code, response.Body.String())
+	}
+}
+
+func TestMapStatusImplicitOK(t *testing.T) {
+	m := New(MapStatus(map[int]int{http.StatusOK: http.StatusAccepted}))
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte("queued"))
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusAccepted || response.Body.String() != "queued" {
+		t.Fatal("Implicit status not mapped: ", response.Code, response.Body.String())
+	}
+}
@@ -0,0 +1,33 @@
+package mezvaro
+
+// WithRouteMeta attaches metadata with provided key and value to Mezvaro
+// instance and its forks, which can be read by handlers with RouteMeta.
+// Route is usually fork registered for single path, so this allows routes to
+// declare things like required permissions, that generic middlewares
+// enforce:
+//
+//	mux.Handle("/admin", m.Fork().With(
+//		mezvaro.WithRouteMeta("permission", "admin"),
+//	).HF(adminHandler))
+//
+// Metadata set on fork overrides metadata with the same key set on parents.
+func WithRouteMeta(key string, value interface{}) Option {
+	return func(m *Mezvaro) {
+		if m.routeMeta == nil {
+			m.routeMeta = make(map[string]interface{})
+		}
+		m.routeMeta[key] = value
+	}
+}
+
+// RouteMeta returns metadata with provided key attached with WithRouteMeta to
+// Mezvaro instance serving request, or to its closest parent. If metadata
+// with key does not exist, nil is returned.
+func (c *Context) RouteMeta(key string) interface{} {
+	for current := c.mezvaro; current != nil; current = current.parent {
+		if value, ok := current.routeMeta[key]; ok {
+			return value
+		}
+	}
+	return nil
+}
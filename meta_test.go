@@ -0,0 +1,52 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMeta(t *testing.T) {
+	authorize := HandlerFunc(func(c *Context) {
+		permission, _ := c.RouteMeta("permission").(string)
+		if permission != "" && c.Request.Header.Get("X-Permission") != permission {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	})
+	m := New(authorize).With(WithRouteMeta("owner", "team"))
+	mux := http.NewServeMux()
+	mux.Handle("/public", m.HF(func(c *Context) {}))
+	mux.Handle("/admin", m.Fork().With(WithRouteMeta("permission", "admin")).HF(func(c *Context) {
+		if c.RouteMeta("owner") != "team" {
+			t.Fatal("Metadata of parent not visible in fork.")
+		}
+	}))
+
+	for _, tc := range []struct {
+		path       string
+		permission string
+		status     int
+	}{
+		{"/public", "", http.StatusOK},
+		{"/admin", "", http.StatusForbidden},
+		{"/admin", "user", http.StatusForbidden},
+		{"/admin", "admin", http.StatusOK},
+	} {
+		request, _ := http.NewRequest("GET", tc.path, nil)
+		request.Header.Set("X-Permission", tc.permission)
+		response := httptest.NewRecorder()
+		mux.ServeHTTP(response, request)
+		if response.Code != tc.status {
+			t.Fatal("Wrong status for ", tc.path, " with permission ", tc.permission, ": ", response.Code)
+		}
+	}
+}
+
+func TestRouteMetaMissing(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	if c.RouteMeta("permission") != nil {
+		t.Fatal("Metadata found for context outside of chain.")
+	}
+}
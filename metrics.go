@@ -0,0 +1,54 @@
+package mezvaro
+
+import "time"
+
+// RequestMetrics describes handled request, for recording it in metrics
+// system, like Prometheus.
+type RequestMetrics struct {
+	// Method of request.
+	Method string
+	// Route is pattern of route that matched request (see FullPath). It is
+	// used instead of path of request, which would cause unbounded
+	// cardinality of metrics.
+	Route string
+	// Status of response.
+	Status int
+	// Duration of handling request by rest of the chain.
+	Duration time.Duration
+	// Exemplar holds labels that link observation to trace, with trace ID
+	// under "trace_id" and span ID under "span_id" keys, if request carries
+	// traceparent header (see TraceContext). Otherwise it is nil, so no
+	// exemplar should be attached.
+	Exemplar map[string]string
+}
+
+// Metrics returns middleware that measures duration of rest of the chain and
+// passes metrics of each request to provided function. Mezvaro does not
+// depend on any metrics library, so function records metrics. For example,
+// with Prometheus client, latency histogram with exemplars linking
+// observations to traces is recorded with:
+//
+//	mezvaro.Metrics(func(m mezvaro.RequestMetrics) {
+//		observer := latency.WithLabelValues(m.Method, m.Route, strconv.Itoa(m.Status))
+//		if m.Exemplar != nil {
+//			observer.(prometheus.ExemplarObserver).ObserveWithExemplar(m.Duration.Seconds(), m.Exemplar)
+//			return
+//		}
+//		observer.Observe(m.Duration.Seconds())
+//	})
+func Metrics(observe func(RequestMetrics)) Handler {
+	return HandlerFunc(func(c *Context) {
+		start := time.Now()
+		c.Next()
+		metrics := RequestMetrics{
+			Method:   c.Request.Method,
+			Route:    c.FullPath(),
+			Status:   c.responseStatus(),
+			Duration: time.Since(start),
+		}
+		if traceID, spanID, ok := c.TraceContext(); ok {
+			metrics.Exemplar = map[string]string{"trace_id": traceID, "span_id": spanID}
+		}
+		observe(metrics)
+	})
+}
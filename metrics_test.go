@@ -0,0 +1,48 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func observeRequest(traceparent string) RequestMetrics {
+	var observed RequestMetrics
+	m := New(Metrics(func(metrics RequestMetrics) {
+		observed = metrics
+	}))
+	handler := m.HF(func(c *Context) {
+		c.Response.WriteHeader(http.StatusCreated)
+	})
+	request := httptest.NewRequest("POST", "/users/42", nil)
+	// pattern is set like http.ServeMux sets it for matched request
+	request.Pattern = "/users/{id}"
+	if traceparent != "" {
+		request.Header.Set("traceparent", traceparent)
+	}
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	return observed
+}
+
+func TestMetrics(t *testing.T) {
+	metrics := observeRequest("")
+	if metrics.Method != "POST" || metrics.Route != "/users/{id}" || metrics.Status != http.StatusCreated {
+		t.Fatal("Wrong metrics: ", metrics)
+	}
+	if metrics.Duration <= 0 {
+		t.Fatal("Duration not measured.")
+	}
+	if metrics.Exemplar != nil {
+		t.Fatal("Exemplar attached without trace context: ", metrics.Exemplar)
+	}
+}
+
+func TestMetricsExemplar(t *testing.T) {
+	metrics := observeRequest("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if metrics.Exemplar["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatal("Trace ID exemplar not attached: ", metrics.Exemplar)
+	}
+	if metrics.Exemplar["span_id"] != "00f067aa0ba902b7" {
+		t.Fatal("Span ID exemplar not attached: ", metrics.Exemplar)
+	}
+}
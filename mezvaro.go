@@ -1,8 +1,13 @@
 package mezvaro
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // Handler defines interface for Mezvaro middlewares and handlers.
@@ -44,24 +49,182 @@ func SetURLParamsExtractor(extractor URLParamsExtractor) {
 
 // Mezvaro is simply chain of handlers that will be executed in order they are added.
 type Mezvaro struct {
-	parent       *Mezvaro
-	handlerChain []Handler
+	// DefaultContentType is set as Content-Type of responses whose handlers
+	// write body without specifying content type, instead of relying on content
+	// type detection of standard library. If empty, value from parent instance
+	// is used. If none of the parents set it, content type is not changed.
+	DefaultContentType string
+
+	parent              *Mezvaro
+	handlerChain        []Handler
+	phaseHandlers       map[Phase][]Handler
+	defaultHeaders      map[string]string
+	urlParamsExtractors []URLParamsExtractor
+	debug               bool
+	checkCancellation   bool
+	transcodeCharset    bool
+	nilJSON             NilJSON
+	onceKeys            map[string]struct{}
+	routeMeta           map[string]interface{}
+	providers           map[interface{}]func() interface{}
+
+	// detached is set for instances whose chain already includes handlers
+	// of parents, so handlers of parents are not added to it again.
+	detached bool
+
+	// version is incremented every time handler chain of instance changes.
+	version atomic.Uint64
+	// chainCache holds whole chain built for current versions of instance
+	// and its parents.
+	chainCache atomic.Pointer[cachedChain]
+}
+
+// cachedChain is whole chain of handlers built for specific version of tree.
+type cachedChain struct {
+	version  uint64
+	handlers []Handler
+}
+
+// Option configures instance of Mezvaro. Options are applied with With method.
+type Option func(*Mezvaro)
+
+// WithURLParamsExtractor sets URL parameters extractor used by Mezvaro
+// instance and its forks, replacing all extractors previously added to
+// the instance.
+func WithURLParamsExtractor(extractor URLParamsExtractor) Option {
+	return func(m *Mezvaro) {
+		m.urlParamsExtractors = []URLParamsExtractor{extractor}
+	}
+}
+
+// WithDebug enables or disables debug mode for Mezvaro instance and its forks.
+// In debug mode execution of handlers is traced, and trace can be obtained
+// with Context.Trace method.
+func WithDebug(debug bool) Option {
+	return func(m *Mezvaro) {
+		m.debug = debug
+	}
+}
+
+// WithCancellationCheck enables or disables checking of context cancellation
+// between handlers for Mezvaro instance and its forks. When enabled, chain is
+// aborted with 503 Service Unavailable status before next handler is invoked
+// if context has been cancelled or its deadline has passed, so cancelled
+// requests stop promptly. By default, all handlers are invoked regardless of
+// cancellation.
+func WithCancellationCheck(check bool) Option {
+	return func(m *Mezvaro) {
+		m.checkCancellation = check
+	}
 }
 
-// New creates new instance of Mezvaro with provided handlers.
+// New creates new instance of Mezvaro with provided handlers. It panics if
+// any of handlers is nil.
 func New(handlers ...Handler) *Mezvaro {
+	checkHandlers(handlers)
 	return &Mezvaro{
 		parent:       nil,
 		handlerChain: handlers,
 	}
 }
 
-// Use adds new handler to used instance of Mezvaro.
+// Use adds new handler to used instance of Mezvaro. It panics if any of
+// handlers is nil, so misconfigured chain fails when it is built instead of
+// when first request is handled.
 func (m *Mezvaro) Use(handler ...Handler) *Mezvaro {
+	checkHandlers(handler)
 	m.handlerChain = append(m.handlerChain, handler...)
+	m.version.Add(1)
 	return m
 }
 
+// UseIf adds handlers to used instance of Mezvaro only if condition is true.
+// Condition is evaluated once, when handlers are registered, which is useful
+// for handlers that depend on environment, like profiling in development.
+// For handlers that should be executed only for some requests, use ForkWhen.
+func (m *Mezvaro) UseIf(condition bool, handlers ...Handler) *Mezvaro {
+	if !condition {
+		return m
+	}
+	return m.Use(handlers...)
+}
+
+// UseOnce adds handler to used instance of Mezvaro under provided key, unless
+// handler with same key has already been added to this instance or any of its
+// parents. This prevents duplicate execution of handlers like logging or
+// recovery when both library and application add them.
+func (m *Mezvaro) UseOnce(key string, h Handler) *Mezvaro {
+	for current := m; current != nil; current = current.parent {
+		if _, ok := current.onceKeys[key]; ok {
+			return m
+		}
+	}
+	if m.onceKeys == nil {
+		m.onceKeys = make(map[string]struct{})
+	}
+	m.onceKeys[key] = struct{}{}
+	return m.Use(h)
+}
+
+// With applies provided options to used instance of Mezvaro. Options are
+// applied in order they are provided, so if same option is provided more
+// then once, the last one wins.
+func (m *Mezvaro) With(opts ...Option) *Mezvaro {
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// DefaultHeaders sets headers that are added to every response served by
+// used instance of Mezvaro and its forks, like security headers. Headers are
+// set before first handler is executed, so handlers can still change them.
+// Forks can override values of headers set by parents, and header set to
+// empty value is not added at all. Calling DefaultHeaders again adds
+// headers to previously set ones.
+func (m *Mezvaro) DefaultHeaders(headers map[string]string) *Mezvaro {
+	if m.defaultHeaders == nil {
+		m.defaultHeaders = make(map[string]string, len(headers))
+	}
+	for name, value := range headers {
+		m.defaultHeaders[http.CanonicalHeaderKey(name)] = value
+	}
+	m.version.Add(1)
+	return m
+}
+
+// mergedDefaultHeaders returns default headers of this instance and its
+// parents, where values of instance override values of its parents.
+func (m *Mezvaro) mergedDefaultHeaders() map[string]string {
+	var merged map[string]string
+	for current := m; current != nil; current = current.chainParent() {
+		for name, value := range current.defaultHeaders {
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			if _, ok := merged[name]; !ok {
+				merged[name] = value
+			}
+		}
+	}
+	for name, value := range merged {
+		if value == "" {
+			delete(merged, name)
+		}
+	}
+	return merged
+}
+
+// setHeaders creates handler that sets provided headers of response.
+func setHeaders(headers map[string]string) Handler {
+	return HandlerFunc(func(c *Context) {
+		header := c.Response.Header()
+		for name, value := range headers {
+			header.Set(name, value)
+		}
+	})
+}
+
 // UseFunc adds function that matches signature of HandlerFunc to used instance
 // of Mezvaro.
 func (m *Mezvaro) UseFunc(handlerFuncs ...func(*Context)) *Mezvaro {
@@ -107,8 +270,9 @@ func (m *Mezvaro) UseHandlerFunc(handlers ...func(http.ResponseWriter, *http.Req
 }
 
 // Fork creates new instance of Mezvaro with copied handlers from current instance
-// and added new provided handlers.
+// and added new provided handlers. It panics if any of handlers is nil.
 func (m *Mezvaro) Fork(handlers ...Handler) *Mezvaro {
+	checkHandlers(handlers)
 	return &Mezvaro{
 		parent:       m,
 		handlerChain: handlers,
@@ -119,80 +283,345 @@ func (m *Mezvaro) Fork(handlers ...Handler) *Mezvaro {
 	//	return New(n...)
 }
 
+// Map creates new instance of Mezvaro whose chain consists of handlers of
+// whole chain of current instance (including its parents), each wrapped with
+// fn, in the same order. It is useful for adding behavior like recovery or
+// timing around every handler:
+//
+//	timed := m.Map(func(h mezvaro.Handler) mezvaro.Handler {
+//		return mezvaro.HandlerFunc(func(c *mezvaro.Context) {
+//			start := time.Now()
+//			h.Handle(c)
+//			log.Println(time.Since(start))
+//		})
+//	})
+//
+// Current instance is not modified. New instance is fork of current one, so
+// it inherits its settings, like options and providers, but its chain is
+// snapshot: handlers added later to current instance or its parents are not
+// part of it, and handlers added to new instance are not wrapped. It panics
+// if fn returns nil handler.
+func (m *Mezvaro) Map(fn func(Handler) Handler) *Mezvaro {
+	chain := m.wholeChain()
+	for i, h := range chain {
+		chain[i] = fn(h)
+	}
+	checkHandlers(chain)
+	return &Mezvaro{
+		parent:       m,
+		handlerChain: chain,
+		detached:     true,
+	}
+}
+
+// ForkWhen creates fork of current instance, like Fork does, but provided
+// handlers are executed only for requests for which predicate returns true.
+// For other requests fork behaves like its parent. Predicate is evaluated once
+// per request, when execution reaches position of handlers in chain.
+func (m *Mezvaro) ForkWhen(predicate func(*http.Request) bool, handlers ...Handler) *Mezvaro {
+	checkHandlers(handlers)
+	return m.Fork(HandlerFunc(func(c *Context) {
+		if predicate(c.Request) {
+			// splice handlers into chain right after current position, so
+			// they are executed as regular part of chain
+			chain := make([]Handler, 0, len(c.handlerChain)+len(handlers))
+			chain = append(chain, c.handlerChain[:c.index+1]...)
+			chain = append(chain, handlers...)
+			c.handlerChain = append(chain, c.handlerChain[c.index+1:]...)
+		}
+		c.Next()
+	}))
+}
+
+// warnDuplicateHandler logs warning if handler is already part of chain, since
+// in that case it would be executed twice. HandlerFunc values are compared by
+// function, so closures created by the same function are considered equal.
+func warnDuplicateHandler(h Handler, chain []Handler) {
+	for _, other := range chain {
+		if sameHandler(h, other) {
+			log.Printf("mezvaro: handler %s is already in chain and will be executed twice", handlerName(h))
+			return
+		}
+	}
+}
+
+// sameHandler checks if two handlers are the same.
+func sameHandler(a, b Handler) bool {
+	af, aok := a.(HandlerFunc)
+	bf, bok := b.(HandlerFunc)
+	if aok || bok {
+		return aok && bok && reflect.ValueOf(af).Pointer() == reflect.ValueOf(bf).Pointer()
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && reflect.TypeOf(a).Comparable() && a == b
+}
+
+// checkHandlers panics if any of provided handlers is nil, including nil
+// function converted to HandlerFunc.
+func checkHandlers(handlers []Handler) {
+	for i, h := range handlers {
+		if hf, ok := h.(HandlerFunc); h == nil || ok && hf == nil {
+			panic(fmt.Sprintf("mezvaro: nil handler at position %d", i))
+		}
+	}
+}
+
 // wholeChain returns whole chain of handlers including this Mezvaro instance
-// and all its parents.
+// and all its parents, with handlers registered for phases (see UsePhase).
 func (m *Mezvaro) wholeChain() []Handler {
+	return m.buildChain(m.regularChain())
+}
+
+// buildChain builds whole chain from provided handlers, by adding handlers
+// of phases and handler that sets default headers.
+func (m *Mezvaro) buildChain(handlers []Handler) []Handler {
+	handlers = m.withPhases(handlers)
+	if headers := m.mergedDefaultHeaders(); len(headers) > 0 {
+		handlers = append([]Handler{setHeaders(headers)}, handlers...)
+	}
+	return handlers
+}
+
+// regularChain returns chain of handlers added with Use, including this
+// Mezvaro instance and all its parents.
+func (m *Mezvaro) regularChain() []Handler {
 	// count number of handler in entire chain first, to allocate slice
 	// of right size right away.
 	var handlerNo int
-	current := m
-	// capacity of 5 is just a guess, most of the time no more the 5
-	// instance will be in chain, so this should be enough to avoid
-	// new allocations during append.
-	parents := make([]*Mezvaro, 0, 5)
-	for current != nil {
-		parents = append(parents, current)
+	for current := m; current != nil; current = current.chainParent() {
 		handlerNo += len(current.handlerChain)
-		current = current.parent
 	}
-	// allocate slice with array of appropriate size
-	// this prevents dynamic expansion of array during appending
-	handlers := make([]Handler, 0, handlerNo)
-	// traverse parents in revers order, since that order of middlewares
-	// is expected
-	for i := len(parents) - 1; i >= 0; i-- {
-		handlers = append(handlers, parents[i].handlerChain...)
+	handlers := make([]Handler, handlerNo)
+	// handlers of parents go before handlers of their forks, so fill slice
+	// from the end while traversing parents. This way there is no need for
+	// keeping list of parents, regardless of depth of the tree.
+	end := handlerNo
+	for current := m; current != nil; current = current.chainParent() {
+		end -= len(current.handlerChain)
+		copy(handlers[end:], current.handlerChain)
 	}
 	return handlers
 }
 
+// chainParent returns parent whose handlers are part of chain of instance,
+// or nil if there is no such parent.
+func (m *Mezvaro) chainParent() *Mezvaro {
+	if m.detached {
+		return nil
+	}
+	return m.parent
+}
+
+// cachedWholeChain returns whole chain of handlers like wholeChain does, but
+// chain is built only once and reused until handlers are added to instance
+// or any of its parents. Returned slice is shared and must not be modified.
+func (m *Mezvaro) cachedWholeChain() []Handler {
+	version := m.treeVersion()
+	if cached := m.chainCache.Load(); cached != nil && cached.version == version {
+		return cached.handlers
+	}
+	handlers := m.wholeChain()
+	m.chainCache.Store(&cachedChain{version: version, handlers: handlers})
+	return handlers
+}
+
+// treeVersion returns sum of versions of instance and all its parents. Since
+// versions only grow, sum changes whenever chain of any of them changes.
+func (m *Mezvaro) treeVersion() uint64 {
+	var version uint64
+	for current := m; current != nil; current = current.chainParent() {
+		version += current.version.Load()
+	}
+	return version
+}
+
+// AddURLParamsExtractor adds URL parameters extractor to used instance of
+// Mezvaro. Extractors are tried in order until one of them returns non-nil
+// map, which allows composing extractors for different routers. Extractors of
+// instance are tried first, in order they are added, then extractors of its
+// parents and at the end extractor set by SetURLParamsExtractor.
+func (m *Mezvaro) AddURLParamsExtractor(extractor URLParamsExtractor) *Mezvaro {
+	m.urlParamsExtractors = append(m.urlParamsExtractors, extractor)
+	return m
+}
+
+// extractURLParams extracts URL parameters from request using extractors
+// configured for this instance and its parents. If none of them returns
+// parameters, globally set extractor is used.
+func (m *Mezvaro) extractURLParams(r *http.Request) map[string]string {
+	for current := m; current != nil; current = current.parent {
+		for _, extractor := range current.urlParamsExtractors {
+			if params := extractor(r); params != nil {
+				return params
+			}
+		}
+	}
+	return urlParamsExtractor(r)
+}
+
 // H builds entire chain of middlewares and adds provided handler at the end.
 // This function exists for optimisation, to avoid building middleware
-// chain in runtime, so we are building it at boot up time.
-func (m *Mezvaro) H(h Handler) http.Handler {
-	wholeChain := append(m.wholeChain(), h)
+// chain in runtime, so we are building it at boot up time. Handlers returned
+// by H are recommended for hot paths, since chain is built only once and it
+// is not affected by later changes to Mezvaro instance or its parents.
+//
+// Optional middlewares are specific to this handler. They are executed after
+// middlewares of Mezvaro instance and before handler, which avoids creating
+// fork for every handler that needs additional middlewares.
+//
+// In debug mode (see WithDebug), warning is logged if handler is already part
+// of chain, for example when it has also been added with Use, since it would
+// be executed twice.
+func (m *Mezvaro) H(h Handler, middleware ...Handler) http.Handler {
+	checkHandlers(middleware)
+	checkHandlers([]Handler{h})
+	wholeChain := append(m.regularChain(), middleware...)
+	if m.isDebug() {
+		warnDuplicateHandler(h, wholeChain)
+	}
+	// handler belongs to PhaseHandler, so it is executed before handlers
+	// registered for later phases
+	wholeChain = m.buildChain(append(wholeChain, h))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		c := newContext(w, r, wholeChain, urlParamsExtractor(r))
-		c.Next()
+		m.serve(w, r, wholeChain)
 	})
 }
 
 // HF builds entire chain of middlewares and adds provided handler func at the end.
 // this function exists for optimization, to avoid building middleware
-// chain in runtime, so we are building it at boot time.
-func (m *Mezvaro) HF(h func(*Context)) http.Handler {
-	return m.H(HandlerFunc(h))
+// chain in runtime, so we are building it at boot time. Optional middlewares
+// are handled same as in H.
+func (m *Mezvaro) HF(h func(*Context), middleware ...Handler) http.Handler {
+	return m.H(HandlerFunc(h), middleware...)
+}
+
+// AsStdMiddleware converts Mezvaro to middleware in format popular in Go
+// community (func(http.Handler) http.Handler), so Mezvaro chain can be embedded
+// in other frameworks. This is inverse of UseHandlerMiddleware.
+//
+// Wrapped handler is executed as final handler of chain, unless chain is
+// aborted. It receives response and request from context, so replacements
+// done by middlewares in chain are visible to it, and request carries Mezvaro
+// context, so deadlines and values set by middlewares are visible through
+// request's Context method.
+func (m *Mezvaro) AsStdMiddleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return m.H(WrapHandler(next))
+	}
 }
 
-// ServeHTTP implements http.Handler interface.
+// ServeHTTP implements http.Handler interface. Whole chain is built on first
+// request and reused until handlers are added to instance or its parents.
+// Items waiting in batches of request (see Context.Batch) are flushed after
+// chain finishes.
+// If chain is aborted, unread part of request body is discarded, up to
+// MaxDrainSize bytes, so connection can be reused.
 func (m *Mezvaro) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	c := newContext(w, r, m.wholeChain(), urlParamsExtractor(r))
+	m.serve(w, r, m.cachedWholeChain())
+}
+
+// serve creates context for request and executes provided handler chain in it.
+func (m *Mezvaro) serve(w http.ResponseWriter, r *http.Request, handlerChain []Handler) {
+	c := newContext(nil, r, handlerChain, m.extractURLParams(r))
+	// response writer is part of context, so only one allocation is needed
+	// per request
+	c.writer = responseWriter{
+		ResponseWriter:     w,
+		defaultContentType: m.defaultContentType(),
+	}
+	c.Response = &c.writer
+	c.mezvaro = m
+	c.tracing = m.isDebug()
+	c.checkCancellation = m.isCancellationChecked()
 	c.Next()
+	c.FlushBatches()
+	if c.IsAborted() {
+		c.drainBody()
+	}
 }
 
-// Handle implements Handler interface.
+// isDebug checks if debug mode is enabled for this instance or any of its
+// parents.
+func (m *Mezvaro) isDebug() bool {
+	for current := m; current != nil; current = current.parent {
+		if current.debug {
+			return true
+		}
+	}
+	return false
+}
+
+// isCancellationChecked checks if cancellation check is enabled for this
+// instance or any of its parents.
+func (m *Mezvaro) isCancellationChecked() bool {
+	for current := m; current != nil; current = current.parent {
+		if current.checkCancellation {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultContentType returns default content type configured for this
+// instance or its closest parent.
+func (m *Mezvaro) defaultContentType() string {
+	for current := m; current != nil; current = current.parent {
+		if current.DefaultContentType != "" {
+			return current.DefaultContentType
+		}
+	}
+	return ""
+}
+
+// Handle implements Handler interface, so Mezvaro can be used as part of
+// chain of other Mezvaro instance. Chain of this instance is executed on copy
+// of provided context, that shares request and response with it and
+// preserves stuff like timeout, deadline and values, while providers and
+// route metadata are those of this instance. Position in chain of provided
+// context is not affected, so Handle can be called concurrently with the same
+// context, for example for executing parallel sub-chains. Changes made to
+// context by handlers of this instance are not visible through provided
+// context.
+//
+// If chain of this instance is aborted, for example by authentication
+// middleware, provided context is aborted too, so rest of outer chain is not
+// executed. Because of that, sub-chains executed concurrently must not abort.
 func (m *Mezvaro) Handle(c *Context) {
-	// Reuse provided context, since request and response has to be the same
-	// and stuff like timeout and deadline has to be preserved.
-	c.handlerChain = m.wholeChain()
-	c.index = -1
-	c.Next()
+	sub := c.clone()
+	sub.handlerChain = m.cachedWholeChain()
+	sub.mezvaro = m
+	sub.Next()
+	sub.FlushBatches()
+	if sub.IsAborted() {
+		c.Abort()
+	}
 }
 
 // WrapHandlerMiddleware wraps middleware defined in format popular in bunch
-// of other Go frameworks to Handler compatible with Mezvaro.
+// of other Go frameworks to Handler compatible with Mezvaro. Request passed
+// to middleware carries Mezvaro context, and request and response passed by
+// middleware to next handler replace ones in context (see SetRequest and
+// SetResponse). If middleware panics while constructing handler or returns
+// nil handler, request fails with 500 status and error is recorded with
+// Error method.
 func WrapHandlerMiddleware(middleware func(http.Handler) http.Handler) Handler {
+	if middleware == nil {
+		panic("mezvaro: nil handler middleware")
+	}
 	fn := func(c *Context) {
 		var calledNext bool
-		handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler, err := buildHandler(middleware, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			calledNext = true
 			// replace response and request objects with one provided from middleware,
 			// since middleware might want to replace them with something similar
-			c.Response = w
-			c.Request = r
+			c.SetResponse(w)
+			c.SetRequest(r)
 			c.Next()
 		}))
-		handler.ServeHTTP(c.Response, c.Request)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		handler.ServeHTTP(c.Response, c.requestWithContext())
 		if !calledNext {
 			// standard way of aborting chain for this style of middleware is
 			// not to call next handler, so if next handler was not called,
@@ -203,12 +632,35 @@ func WrapHandlerMiddleware(middleware func(http.Handler) http.Handler) Handler {
 	return HandlerFunc(fn)
 }
 
+// buildHandler constructs handler from handler middleware. Panic of
+// middleware and nil handler returned by it are reported as error, so broken
+// middleware fails single request instead of crashing server.
+func buildHandler(middleware func(http.Handler) http.Handler, next http.Handler) (handler http.Handler, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("mezvaro: handler middleware panicked: %v", r)
+		}
+	}()
+	handler = middleware(next)
+	if handler == nil {
+		return nil, errors.New("mezvaro: handler middleware returned nil handler")
+	}
+	return handler, nil
+}
+
 // WrapHandler wraps standard library handler to Mezvaro handler. This handler
 // can be used as middleware (next middleware is automatically called) or it
 // can be used as final handler.
+//
+// Request passed to wrapped handler carries Mezvaro context, so deadlines,
+// cancellation and values set by previous middlewares are visible through
+// request's Context method.
 func WrapHandler(handler http.Handler) Handler {
+	if hf, ok := handler.(http.HandlerFunc); handler == nil || ok && hf == nil {
+		panic("mezvaro: nil http handler")
+	}
 	return HandlerFunc(func(c *Context) {
-		handler.ServeHTTP(c.Response, c.Request)
+		handler.ServeHTTP(c.Response, c.requestWithContext())
 		c.Next()
 	})
 }
@@ -1,9 +1,18 @@
 package mezvaro
 
 import (
+	"bytes"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/net/context"
 )
 
 func TestCreateEmpty(t *testing.T) {
@@ -77,6 +86,69 @@ func TestUseHandlerMiddleware(t *testing.T) {
 	}
 }
 
+func TestWrapHandlerMiddlewareBroken(t *testing.T) {
+	middlewares := map[string]func(http.Handler) http.Handler{
+		"nil":   func(h http.Handler) http.Handler { return nil },
+		"panic": func(h http.Handler) http.Handler { panic("broken") },
+	}
+	for name, middleware := range middlewares {
+		var called bool
+		var errs []error
+		m := New(HandlerFunc(func(c *Context) {
+			c.Next()
+			errs = c.Errors()
+		}))
+		m.UseHandlerMiddleware(middleware)
+		m.Use(HandlerFunc(func(c *Context) { called = true }))
+		recorder := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/", nil)
+		m.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusInternalServerError {
+			t.Fatal("Wrong status for ", name, " middleware: ", recorder.Code)
+		}
+		if called {
+			t.Fatal("Chain not aborted for ", name, " middleware.")
+		}
+		if len(errs) != 1 {
+			t.Fatal("Error not recorded for ", name, " middleware: ", errs)
+		}
+	}
+}
+
+func TestUseIf(t *testing.T) {
+	m := New()
+	m.UseIf(false, HandlerFunc(func(c *Context) {}))
+	if len(m.handlerChain) != 0 {
+		t.Fatal("Handlers added when condition does not hold.")
+	}
+	m.UseIf(true, HandlerFunc(func(c *Context) {}), HandlerFunc(func(c *Context) {}))
+	if len(m.handlerChain) != 2 {
+		t.Fatal("Expected 2 handlers, found: ", len(m.handlerChain))
+	}
+}
+
+func TestNilHandlerRejected(t *testing.T) {
+	registrations := map[string]func(m *Mezvaro){
+		"Use":                  func(m *Mezvaro) { m.Use(nil) },
+		"UseFunc":              func(m *Mezvaro) { m.UseFunc(nil) },
+		"UseHandler":           func(m *Mezvaro) { m.UseHandler(nil) },
+		"UseHandlerFunc":       func(m *Mezvaro) { m.UseHandlerFunc(nil) },
+		"UseHandlerMiddleware": func(m *Mezvaro) { m.UseHandlerMiddleware(nil) },
+		"Fork":                 func(m *Mezvaro) { m.Fork(HandlerFunc(nil)) },
+		"HF":                   func(m *Mezvaro) { m.HF(nil) },
+	}
+	for name, register := range registrations {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("Nil handler accepted by ", name)
+				}
+			}()
+			register(New())
+		}()
+	}
+}
+
 func TestForkHandlerCount(t *testing.T) {
 	original := HandlerFunc(func(c *Context) {})
 	forkHandler := HandlerFunc(func(c *Context) {})
@@ -117,6 +189,101 @@ func TestHandle(t *testing.T) {
 	}
 }
 
+func TestHandleConcurrent(t *testing.T) {
+	var calls int64
+	count := HandlerFunc(func(c *Context) {
+		atomic.AddInt64(&calls, 1)
+		c.Next()
+	})
+	sub := New(count, count, count)
+	var after int
+	m := New(HandlerFunc(func(c *Context) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				sub.Handle(c)
+			}()
+		}
+		wg.Wait()
+		c.Next()
+	}), HandlerFunc(func(c *Context) {
+		after++
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if calls != 30 {
+		t.Fatal("Expected all handlers of sub-chains to run, got: ", calls)
+	}
+	if after != 1 {
+		t.Fatal("Position in parent chain corrupted by sub-chains, calls: ", after)
+	}
+}
+
+func TestHandleAbortPropagated(t *testing.T) {
+	auth := New(HandlerFunc(func(c *Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	}))
+	m := New(auth, HandlerFunc(func(c *Context) {
+		c.Response.Write([]byte("secret"))
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusUnauthorized || response.Body.Len() != 0 {
+		t.Fatal("Outer chain executed after sub-chain aborted: ", response.Code, response.Body.String())
+	}
+}
+
+func TestHandleUsesOwnInstance(t *testing.T) {
+	var value, meta interface{}
+	sub := New(HandlerFunc(func(c *Context) {
+		value = c.Resolve("db")
+		meta = c.RouteMeta("scope")
+	})).With(WithRouteMeta("scope", "admin"))
+	sub.Provide("db", func() interface{} { return "sub-db" })
+	New(sub).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if value != "sub-db" || meta != "admin" {
+		t.Fatal("Providers and metadata of mounted instance not visible: ", value, meta)
+	}
+}
+
+func TestHandleResolvesWithOwnProviders(t *testing.T) {
+	var outer, inner interface{}
+	sub := New(HandlerFunc(func(c *Context) {
+		inner = c.Resolve("db")
+	}))
+	sub.Provide("db", func() interface{} { return "sub-db" })
+	m := New(HandlerFunc(func(c *Context) {
+		outer = c.Resolve("db")
+		c.Next()
+	}), sub)
+	m.Provide("db", func() interface{} { return "outer-db" })
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if outer != "outer-db" || inner != "sub-db" {
+		t.Fatal("Instance resolved by outer chain used in mounted instance: ", outer, inner)
+	}
+}
+
+func TestHandlePreservesContext(t *testing.T) {
+	type key int
+	var value interface{}
+	sub := New(HandlerFunc(func(c *Context) {
+		value = c.Value(key(1))
+		c.WithValue(key(1), "sub")
+	}))
+	m := New(HandlerFunc(func(c *Context) {
+		c.WithValue(key(1), "parent")
+		c.Next()
+		if c.Value(key(1)) != "parent" {
+			t.Fatal("Change made by sub-chain visible in parent context.")
+		}
+	}), sub)
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if value != "parent" {
+		t.Fatal("Value of parent context not visible in sub-chain: ", value)
+	}
+}
+
 func TestWrapHandlerMiddleware(t *testing.T) {
 	var called bool
 	middleware := func(h http.Handler) http.Handler {
@@ -147,7 +314,7 @@ func TestWrapHandlerMiddlewareReplaceResponseRequest(t *testing.T) {
 	handler := WrapHandlerMiddleware(middleware)
 	ctx := &Context{Response: originalResponse, Request: originalRequest}
 	handler.Handle(ctx)
-	if ctx.Response != replacementResponse {
+	if rw, ok := ctx.Response.(*responseWriter); !ok || rw.Unwrap() != replacementResponse {
 		t.Fatal("Response not replaced by handler middleware.")
 	}
 	if ctx.Request != replacementRequest {
@@ -155,6 +322,51 @@ func TestWrapHandlerMiddlewareReplaceResponseRequest(t *testing.T) {
 	}
 }
 
+func TestWrapHandlerMiddlewareContextSync(t *testing.T) {
+	type key int
+	var value interface{}
+	var written bool
+	var contentType string
+	m := New()
+	m.DefaultContentType = "text/plain; charset=utf-8"
+	m.UseFunc(func(c *Context) {
+		c.WithValue(key(1), "mezvaro")
+		c.Response.WriteHeader(http.StatusAccepted)
+		c.Next()
+	})
+	m.UseHandlerMiddleware(func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r = r.WithContext(context.WithValue(r.Context(), key(2), "middleware"))
+			h.ServeHTTP(httptest.NewRecorder(), r)
+		})
+	})
+	m.UseFunc(func(c *Context) {
+		value = c.Value(key(1)).(string) + "," + c.Value(key(2)).(string)
+		written = c.Written()
+		contentType = c.Response.(*responseWriter).defaultContentType
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if value != "mezvaro,middleware" {
+		t.Fatal("Context not synchronized with replaced request: ", value)
+	}
+	if !written {
+		t.Fatal("Written state lost after response replacement.")
+	}
+	if contentType != "text/plain; charset=utf-8" {
+		t.Fatal("Default content type lost after response replacement.")
+	}
+}
+
+func TestSetResponseKeepsWrapper(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	rw := &responseWriter{ResponseWriter: httptest.NewRecorder()}
+	c.SetResponse(rw)
+	if c.Response != rw {
+		t.Fatal("Capturing writer wrapped again.")
+	}
+}
+
 func TestWrapHandlerMiddlewareAbort(t *testing.T) {
 	response := httptest.NewRecorder()
 	request, _ := http.NewRequest("GET", "", nil)
@@ -189,6 +401,37 @@ func TestWrapHandler(t *testing.T) {
 	}
 }
 
+func TestWrapHandlerRequestContext(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	var requestDeadline time.Time
+	var ok bool
+	m := New()
+	m.UseFunc(func(c *Context) {
+		cancel := c.WithDeadline(deadline)
+		defer cancel()
+		c.Next()
+	})
+	m.UseHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestDeadline, ok = r.Context().Deadline()
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), request)
+	if !ok || !requestDeadline.Equal(deadline) {
+		t.Fatal("Wrapped handler does not see deadline set by middleware.")
+	}
+}
+
+func TestWrapHandlerNilRequest(t *testing.T) {
+	var called bool
+	handler := WrapHandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	handler.Handle(newContext(httptest.NewRecorder(), nil, nil, nil))
+	if !called {
+		t.Fatal("Handler not called.")
+	}
+}
+
 func TestWrapHandlerFunc(t *testing.T) {
 	response := httptest.NewRecorder()
 	request, _ := http.NewRequest("GET", "", nil)
@@ -238,3 +481,470 @@ func TestCustomParamsExtractor(t *testing.T) {
 		}
 	}
 }
+
+func TestWithOptions(t *testing.T) {
+	var urlParams map[string]string
+	handler := HandlerFunc(func(c *Context) {
+		urlParams = c.urlParams
+	})
+	first := func(r *http.Request) map[string]string {
+		return map[string]string{"param": "first"}
+	}
+	second := func(r *http.Request) map[string]string {
+		return map[string]string{"param": "second"}
+	}
+	m := New().With(
+		WithURLParamsExtractor(first),
+		func(m *Mezvaro) { m.Use(handler) },
+		WithURLParamsExtractor(second),
+	)
+	if len(m.handlerChain) != 1 {
+		t.Fatal("Expected 1 handler, found: ", len(m.handlerChain))
+	}
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if urlParams["param"] != "second" {
+		t.Fatal("Last provided extractor not used.")
+	}
+}
+
+func TestWithURLParamsExtractorInheritedByFork(t *testing.T) {
+	var urlParams map[string]string
+	extractor := func(r *http.Request) map[string]string {
+		return map[string]string{"param": "instance"}
+	}
+	m := New().With(WithURLParamsExtractor(extractor))
+	fork := m.Fork(HandlerFunc(func(c *Context) {
+		urlParams = c.urlParams
+	}))
+	fork.ServeHTTP(httptest.NewRecorder(), nil)
+	if urlParams["param"] != "instance" {
+		t.Fatal("Fork does not use extractor of parent.")
+	}
+}
+
+func TestAddURLParamsExtractorFallback(t *testing.T) {
+	var urlParams map[string]string
+	var firstCalled bool
+	m := New(HandlerFunc(func(c *Context) {
+		urlParams = c.urlParams
+	}))
+	m.AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		firstCalled = true
+		return nil
+	})
+	m.AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		return map[string]string{"param": "second"}
+	})
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if !firstCalled {
+		t.Fatal("First extractor not called.")
+	}
+	if urlParams["param"] != "second" {
+		t.Fatal("Parameters of second extractor not used.")
+	}
+}
+
+func TestAddURLParamsExtractorFirstWins(t *testing.T) {
+	var urlParams map[string]string
+	var secondCalled bool
+	m := New(HandlerFunc(func(c *Context) {
+		urlParams = c.urlParams
+	}))
+	m.AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		return map[string]string{"param": "first"}
+	})
+	m.AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		secondCalled = true
+		return map[string]string{"param": "second"}
+	})
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if secondCalled {
+		t.Fatal("Second extractor called after first returned parameters.")
+	}
+	if urlParams["param"] != "first" {
+		t.Fatal("Parameters of first extractor not used.")
+	}
+}
+
+func TestAddURLParamsExtractorParentFallback(t *testing.T) {
+	var urlParams map[string]string
+	m := New().AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		return map[string]string{"param": "parent"}
+	})
+	fork := m.Fork(HandlerFunc(func(c *Context) {
+		urlParams = c.urlParams
+	}))
+	fork.AddURLParamsExtractor(func(r *http.Request) map[string]string {
+		return nil
+	})
+	fork.ServeHTTP(httptest.NewRecorder(), nil)
+	if urlParams["param"] != "parent" {
+		t.Fatal("Parent extractor not used as fallback.")
+	}
+}
+
+func TestUseOnce(t *testing.T) {
+	m := New()
+	m.UseOnce("logging", HandlerFunc(func(c *Context) {}))
+	m.UseOnce("logging", HandlerFunc(func(c *Context) {}))
+	m.UseOnce("recovery", HandlerFunc(func(c *Context) {}))
+	if len(m.handlerChain) != 2 {
+		t.Fatal("Expected 2 handlers, found: ", len(m.handlerChain))
+	}
+}
+
+func TestUseOnceParent(t *testing.T) {
+	m := New().UseOnce("logging", HandlerFunc(func(c *Context) {}))
+	fork := m.Fork()
+	fork.UseOnce("logging", HandlerFunc(func(c *Context) {}))
+	if len(fork.handlerChain) != 0 {
+		t.Fatal("Handler with key present in parent added to fork.")
+	}
+	if len(fork.wholeChain()) != 1 {
+		t.Fatal("Expected 1 handler in entire chain, found: ", len(fork.wholeChain()))
+	}
+}
+
+func TestAsStdMiddleware(t *testing.T) {
+	type key int
+	var value interface{}
+	var outerCalled bool
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("X-Mezvaro", "yes")
+		c.WithValue(key(1), "from mezvaro")
+		c.Next()
+	}))
+	final := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value = r.Context().Value(key(1))
+		w.Write([]byte("final"))
+	})
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			outerCalled = true
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := outer(m.AsStdMiddleware()(final))
+
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(response, request)
+	if !outerCalled {
+		t.Fatal("Outer middleware not called.")
+	}
+	if response.Header().Get("X-Mezvaro") != "yes" {
+		t.Fatal("Mezvaro chain not executed.")
+	}
+	if value != "from mezvaro" {
+		t.Fatal("Value from Mezvaro context not visible to wrapped handler.")
+	}
+	if response.Body.String() != "final" {
+		t.Fatal("Wrapped handler not called.")
+	}
+}
+
+func TestAsStdMiddlewareAbort(t *testing.T) {
+	var called bool
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.WriteHeader(http.StatusUnauthorized)
+		c.Abort()
+	}))
+	handler := m.AsStdMiddleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(response, request)
+	if called {
+		t.Fatal("Wrapped handler called after chain was aborted.")
+	}
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Expected status 401, got: ", response.Code)
+	}
+}
+
+// deepTree creates tree of forked Mezvaro instances with provided depth,
+// where each instance has single handler that records its level.
+func deepTree(depth int, levels *[]int) *Mezvaro {
+	var m *Mezvaro
+	for i := 0; i < depth; i++ {
+		level := i
+		handler := HandlerFunc(func(c *Context) {
+			if levels != nil {
+				*levels = append(*levels, level)
+			}
+		})
+		if m == nil {
+			m = New(handler)
+		} else {
+			m = m.Fork(handler)
+		}
+	}
+	return m
+}
+
+func TestWholeChainDeepTreeOrder(t *testing.T) {
+	var levels []int
+	m := deepTree(12, &levels)
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if len(levels) != 12 {
+		t.Fatal("Expected 12 handlers to be called, got: ", len(levels))
+	}
+	for i, level := range levels {
+		if level != i {
+			t.Fatal("Handlers called in wrong order: ", levels)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	var calls []string
+	record := func(name string) Handler {
+		return HandlerFunc(func(c *Context) { calls = append(calls, name) })
+	}
+	parent := New(record("first")).With(WithRouteMeta("key", "value"))
+	m := parent.Fork(record("second"), record("third"))
+	mapped := m.Map(func(h Handler) Handler {
+		return HandlerFunc(func(c *Context) {
+			calls = append(calls, "before")
+			h.Handle(c)
+		})
+	})
+	mapped.UseFunc(func(c *Context) {
+		if c.RouteMeta("key") != "value" {
+			t.Fatal("Settings of original not inherited.")
+		}
+		calls = append(calls, "added")
+	})
+	mapped.ServeHTTP(httptest.NewRecorder(), nil)
+	expected := "before first before second before third added"
+	if strings.Join(calls, " ") != expected {
+		t.Fatal("Wrong order of calls: ", calls)
+	}
+
+	calls = nil
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if strings.Join(calls, " ") != "first second third" {
+		t.Fatal("Original modified: ", calls)
+	}
+}
+
+func TestDefaultHeaders(t *testing.T) {
+	m := New().DefaultHeaders(map[string]string{
+		"X-Powered-By":    "mezvaro",
+		"x-frame-options": "DENY",
+		"X-Debug":         "true",
+	})
+	fork := m.Fork().DefaultHeaders(map[string]string{
+		"X-Frame-Options": "SAMEORIGIN",
+		"X-Debug":         "",
+	})
+	handler := fork.HF(func(c *Context) {
+		c.Response.Header().Set("X-Powered-By", "handler")
+	})
+
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if response.Header().Get("X-Powered-By") != "mezvaro" || response.Header().Get("X-Frame-Options") != "DENY" ||
+		response.Header().Get("X-Debug") != "true" {
+		t.Fatal("Default headers not set: ", response.Header())
+	}
+
+	response = httptest.NewRecorder()
+	handler.ServeHTTP(response, nil)
+	if response.Header().Get("X-Frame-Options") != "SAMEORIGIN" {
+		t.Fatal("Fork did not override header: ", response.Header())
+	}
+	if _, ok := response.Header()["X-Debug"]; ok {
+		t.Fatal("Header removed by fork is set.")
+	}
+	if response.Header().Get("X-Powered-By") != "handler" {
+		t.Fatal("Handler can not change default header: ", response.Header())
+	}
+}
+
+func BenchmarkWholeChainDeepTree(b *testing.B) {
+	m := deepTree(20, nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.wholeChain()
+	}
+}
+
+func TestCachedWholeChainInvalidation(t *testing.T) {
+	var calls []string
+	m := New(HandlerFunc(func(c *Context) {
+		calls = append(calls, "parent")
+	}))
+	fork := m.Fork(HandlerFunc(func(c *Context) {
+		calls = append(calls, "fork")
+	}))
+	fork.ServeHTTP(httptest.NewRecorder(), nil)
+	if len(fork.cachedWholeChain()) != 2 {
+		t.Fatal("Expected 2 handlers in cached chain.")
+	}
+
+	m.UseFunc(func(c *Context) {
+		calls = append(calls, "parent-added")
+	})
+	fork.UseFunc(func(c *Context) {
+		calls = append(calls, "fork-added")
+	})
+	calls = nil
+	fork.ServeHTTP(httptest.NewRecorder(), nil)
+	expected := []string{"parent", "parent-added", "fork", "fork-added"}
+	if len(calls) != len(expected) {
+		t.Fatal("Cached chain not invalidated: ", calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatal("Handlers called in wrong order: ", calls)
+		}
+	}
+}
+
+func TestCachedWholeChainReused(t *testing.T) {
+	m := deepTree(5, nil)
+	first := m.cachedWholeChain()
+	second := m.cachedWholeChain()
+	if &first[0] != &second[0] {
+		t.Fatal("Cached chain not reused.")
+	}
+}
+
+func BenchmarkServeHTTPDeepTree(b *testing.B) {
+	m := deepTree(20, nil)
+	response := httptest.NewRecorder()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.ServeHTTP(response, nil)
+	}
+}
+
+func TestHandlerSpecificMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) HandlerFunc {
+		return func(c *Context) {
+			calls = append(calls, name)
+		}
+	}
+	m := New(record("global"))
+	mux := http.NewServeMux()
+	mux.Handle("/admin", m.HF(record("admin-handler"), record("auth"), record("audit")))
+	mux.Handle("/public", m.HF(record("public-handler")))
+
+	request, _ := http.NewRequest("GET", "/admin", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), request)
+	expected := []string{"global", "auth", "audit", "admin-handler"}
+	if len(calls) != len(expected) {
+		t.Fatal("Wrong handlers called: ", calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatal("Handlers called in wrong order: ", calls)
+		}
+	}
+
+	calls = nil
+	request, _ = http.NewRequest("GET", "/public", nil)
+	mux.ServeHTTP(httptest.NewRecorder(), request)
+	if len(calls) != 2 || calls[0] != "global" || calls[1] != "public-handler" {
+		t.Fatal("Handler specific middlewares affected other handler: ", calls)
+	}
+}
+
+func benchmarkMezvaro() *Mezvaro {
+	m := New(HandlerFunc(func(c *Context) {}), HandlerFunc(func(c *Context) {}))
+	return m.Fork(HandlerFunc(func(c *Context) {}))
+}
+
+func BenchmarkH(b *testing.B) {
+	handler := benchmarkMezvaro().HF(func(c *Context) {})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(response, request)
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	m := benchmarkMezvaro().UseFunc(func(c *Context) {})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.ServeHTTP(response, request)
+	}
+}
+
+func TestForkWhen(t *testing.T) {
+	var calls []string
+	m := New(HandlerFunc(func(c *Context) {
+		calls = append(calls, "parent")
+		c.Next()
+	}))
+	fork := m.ForkWhen(func(r *http.Request) bool {
+		return r.Header.Get("X-Admin") != ""
+	}, HandlerFunc(func(c *Context) {
+		calls = append(calls, "admin")
+		c.Next()
+	}), HandlerFunc(func(c *Context) {
+		calls = append(calls, "audit")
+		c.Next()
+	}))
+	handler := fork.HF(func(c *Context) {
+		calls = append(calls, "handler")
+	})
+
+	request, _ := http.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Join(calls, ",") != "parent,handler" {
+		t.Fatal("Conditional handlers executed for non matching request: ", calls)
+	}
+
+	calls = nil
+	request.Header.Set("X-Admin", "yes")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Join(calls, ",") != "parent,admin,audit,handler" {
+		t.Fatal("Conditional handlers not executed for matching request: ", calls)
+	}
+
+	calls = nil
+	request.Header.Del("X-Admin")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Join(calls, ",") != "parent,handler" {
+		t.Fatal("Chain modified by previous matching request: ", calls)
+	}
+}
+
+func duplicateHandler(c *Context) {}
+
+func TestHWarnsAboutDuplicateHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	m := New()
+	m.UseFunc(duplicateHandler)
+	m.HF(duplicateHandler)
+	if buf.Len() != 0 {
+		t.Fatal("Warning logged without debug mode: ", buf.String())
+	}
+
+	m.With(WithDebug(true))
+	m.HF(duplicateHandler)
+	if !strings.Contains(buf.String(), "duplicateHandler") {
+		t.Fatal("Duplicate handler not reported: ", buf.String())
+	}
+
+	buf.Reset()
+	m.HF(func(c *Context) {})
+	if buf.Len() != 0 {
+		t.Fatal("Warning logged for handler not in chain: ", buf.String())
+	}
+}
@@ -0,0 +1,108 @@
+// Package mvtest provides utilities for testing Mezvaro handlers in
+// isolation, without building chain and serving requests.
+//
+// Example of testing handler that respects deadlines:
+//
+//	func TestHandler(t *testing.T) {
+//		response := httptest.NewRecorder()
+//		c := mvtest.NewContext(
+//			mvtest.WithResponse(response),
+//			mvtest.WithTimeout(time.Second),
+//		)
+//		MyHandler(c)
+//		// check response
+//	}
+package mvtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/delicb/mezvaro"
+)
+
+// Option configures context created by NewContext.
+type Option func(*options)
+
+type options struct {
+	response http.ResponseWriter
+	request  *http.Request
+	setup    []func(*mezvaro.Context)
+}
+
+// WithRequest sets request of context. By default, GET request for "/"
+// is used.
+func WithRequest(r *http.Request) Option {
+	return func(o *options) {
+		o.request = r
+	}
+}
+
+// WithResponse sets response writer of context, usually
+// *httptest.ResponseRecorder whose content is checked after handler is
+// called. By default, new recorder is used.
+func WithResponse(w http.ResponseWriter) Option {
+	return func(o *options) {
+		o.response = w
+	}
+}
+
+// WithDeadline sets deadline of context.
+func WithDeadline(deadline time.Time) Option {
+	return func(o *options) {
+		o.setup = append(o.setup, func(c *mezvaro.Context) {
+			c.WithDeadline(deadline)
+		})
+	}
+}
+
+// WithTimeout sets deadline of context to provided duration after context
+// is created.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.setup = append(o.setup, func(c *mezvaro.Context) {
+			c.WithTimeout(timeout)
+		})
+	}
+}
+
+// WithValue sets value of context for provided key.
+func WithValue(key, value interface{}) Option {
+	return func(o *options) {
+		o.setup = append(o.setup, func(c *mezvaro.Context) {
+			c.WithValue(key, value)
+		})
+	}
+}
+
+// Cancelled creates context that is already cancelled, for testing how
+// handlers react to requests abandoned by clients.
+func Cancelled() Option {
+	return func(o *options) {
+		o.setup = append(o.setup, func(c *mezvaro.Context) {
+			c.WithCancel()()
+		})
+	}
+}
+
+// NewContext creates context for testing handler, configured with provided
+// options. Options are applied in order they are provided. Resources of
+// deadlines are released when deadline expires, which is fine for tests.
+func NewContext(opts ...Option) *mezvaro.Context {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.request == nil {
+		o.request = httptest.NewRequest("GET", "/", nil)
+	}
+	if o.response == nil {
+		o.response = httptest.NewRecorder()
+	}
+	c := mezvaro.NewContext(o.response, o.request)
+	for _, setup := range o.setup {
+		setup(c)
+	}
+	return c
+}
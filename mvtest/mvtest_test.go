@@ -0,0 +1,64 @@
+package mvtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/delicb/mezvaro"
+)
+
+type key int
+
+func TestNewContextDefaults(t *testing.T) {
+	c := NewContext()
+	if c.Request == nil || c.Request.URL.Path != "/" {
+		t.Fatal("Default request not set.")
+	}
+	if _, ok := c.Deadline(); ok {
+		t.Fatal("Deadline set without option.")
+	}
+	c.Response.WriteHeader(http.StatusNoContent)
+	if !c.Written() {
+		t.Fatal("Response of context not wrapped.")
+	}
+}
+
+func TestNewContextDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Hour)
+	c := NewContext(WithDeadline(deadline))
+	if d, ok := c.Deadline(); !ok || !d.Equal(deadline) {
+		t.Fatal("Deadline not set: ", d)
+	}
+	c = NewContext(WithTimeout(time.Minute))
+	if d, ok := c.Deadline(); !ok || time.Until(d) > time.Minute {
+		t.Fatal("Timeout not set: ", d)
+	}
+}
+
+func TestNewContextValueAndCancel(t *testing.T) {
+	c := NewContext(WithValue(key(1), "value"), Cancelled())
+	if c.Value(key(1)) != "value" {
+		t.Fatal("Value not set.")
+	}
+	if c.Err() == nil {
+		t.Fatal("Context not cancelled.")
+	}
+}
+
+func TestNewContextRequestResponse(t *testing.T) {
+	request := httptest.NewRequest("POST", "/users", nil)
+	response := httptest.NewRecorder()
+	handler := func(c *mezvaro.Context) {
+		c.Response.WriteHeader(http.StatusCreated)
+	}
+	c := NewContext(WithRequest(request), WithResponse(response))
+	handler(c)
+	if c.Request != request {
+		t.Fatal("Request not used.")
+	}
+	if response.Code != http.StatusCreated {
+		t.Fatal("Response not written to provided writer.")
+	}
+}
@@ -0,0 +1,251 @@
+package mezvaro
+
+import (
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats supported by NegotiateFormat.
+const (
+	FormatJSON = "json"
+	FormatXML  = "xml"
+	FormatHTML = "html"
+	FormatText = "text"
+)
+
+// formatMediaTypes maps formats to media types that represent them.
+var formatMediaTypes = map[string][]string{
+	FormatJSON: {"application/json"},
+	FormatXML:  {"application/xml", "text/xml"},
+	FormatHTML: {"text/html"},
+	FormatText: {"text/plain"},
+}
+
+// NegotiateFormat selects one of offered formats (FormatJSON, FormatXML,
+// FormatHTML or FormatText) for response. Format is selected with following
+// precedence:
+//
+//  1. "format" query parameter, for example "?format=json"
+//  2. extension of URL path, for example "/users.json"
+//  3. Accept header, respecting quality values
+//
+// If format is explicitly requested with query parameter or extension, but it
+// is not offered, empty string is returned. If request does not have Accept
+// header, or there is no request, first offered format is returned. If none of offered formats is
+// acceptable, empty string is returned.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	if c.Request == nil {
+		return offered[0]
+	}
+	if format := c.Request.URL.Query().Get("format"); format != "" {
+		return offeredFormat(strings.ToLower(format), offered)
+	}
+	if ext := path.Ext(c.Request.URL.Path); ext != "" {
+		if format := offeredFormat(strings.ToLower(ext[1:]), offered); format != "" {
+			return format
+		}
+	}
+	accept := c.Request.Header.Get("Accept")
+	if accept == "" {
+		return offered[0]
+	}
+	for _, spec := range parseQualityHeader(accept) {
+		for _, format := range offered {
+			for _, mediaType := range formatMediaTypes[format] {
+				if mediaTypeMatches(spec.value, mediaType) {
+					return format
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// RequireAccept returns middleware that responds with 406 Not Acceptable
+// status and aborts chain, unless Accept header of request includes at least
+// one of provided media types, either directly or through wildcard like
+// "application/*" or "*/*". Requests without Accept header are rejected too,
+// so clients have to declare what they can handle.
+func RequireAccept(types ...string) Handler {
+	supported := make([]string, len(types))
+	for i, t := range types {
+		supported[i] = strings.ToLower(t)
+	}
+	return HandlerFunc(func(c *Context) {
+		for _, spec := range parseQualityHeader(c.Request.Header.Get("Accept")) {
+			for _, mediaType := range supported {
+				if mediaTypeMatches(spec.value, mediaType) {
+					c.Next()
+					return
+				}
+			}
+		}
+		http.Error(c.Response, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		c.Abort()
+	})
+}
+
+// PreferredLanguage returns one of supported language tags (like "en" or
+// "pt-BR") that best matches Accept-Language header of request, respecting
+// quality values. Requested tag matches supported tag if they are equal, or
+// if they have the same primary language, for example "en-US" matches "en"
+// and "en" matches "en-GB", but exact matches are preferred. If none of
+// supported languages is acceptable, or header is missing, first supported
+// language is returned as default. Tags are compared case insensitively and
+// returned as provided in supported.
+func (c *Context) PreferredLanguage(supported ...string) string {
+	if len(supported) == 0 {
+		return ""
+	}
+	if c.Request == nil {
+		return supported[0]
+	}
+	for _, spec := range parseQualityHeader(c.Request.Header.Get("Accept-Language")) {
+		if spec.value == "*" {
+			return supported[0]
+		}
+		for _, tag := range supported {
+			if strings.EqualFold(tag, spec.value) {
+				return tag
+			}
+		}
+		requested := primaryLanguage(spec.value)
+		for _, tag := range supported {
+			if primaryLanguage(strings.ToLower(tag)) == requested {
+				return tag
+			}
+		}
+	}
+	return supported[0]
+}
+
+// primaryLanguage returns primary language subtag of language tag.
+func primaryLanguage(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
+
+// offeredFormat returns format if it is one of offered formats, otherwise
+// empty string.
+func offeredFormat(format string, offered []string) string {
+	for _, o := range offered {
+		if o == format {
+			return format
+		}
+	}
+	return ""
+}
+
+// mediaTypeMatches checks if media type matches pattern from Accept header,
+// which can contain wildcards, like "text/*" or "*/*".
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, pattern[:len(pattern)-1])
+	}
+	return false
+}
+
+// qualitySpec is single value from header with quality values, like Accept.
+type qualitySpec struct {
+	value string
+	q     float64
+}
+
+// parseQualityHeader parses value of header with quality values, like
+// Accept, Accept-Encoding or Accept-Language. Values are lowercased and
+// returned ordered by quality, from highest to lowest, preserving order of
+// values with same quality. Values with quality 0 are not returned.
+func parseQualityHeader(header string) []qualitySpec {
+	specs := parseQualityValues(header)
+	n := 0
+	for _, spec := range specs {
+		if spec.q > 0 {
+			specs[n] = spec
+			n++
+		}
+	}
+	specs = specs[:n]
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].q > specs[j].q
+	})
+	return specs
+}
+
+// parseQualityValues parses value of header with quality values in order
+// values appear in header, including values with quality 0.
+func parseQualityValues(header string) []qualitySpec {
+	var specs []qualitySpec
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(param[2:], 64)
+				if err != nil || parsed < 0 || parsed > 1 {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		specs = append(specs, qualitySpec{value: value, q: q})
+	}
+	return specs
+}
+
+// AcceptsEncoding checks if client accepts response in content coding with
+// provided name, like "gzip" or "br", according to Accept-Encoding header of
+// request. Codings explicitly listed with quality 0 are not accepted, and
+// wildcard "*" applies to codings not listed in header. Identity coding is
+// accepted unless it is explicitly disabled, either directly or through
+// wildcard. If request has no Accept-Encoding header, only identity coding
+// is accepted.
+func (c *Context) AcceptsEncoding(encoding string) bool {
+	return encodingQuality(c.Request, encoding) > 0
+}
+
+// encodingQuality returns quality of content coding according to
+// Accept-Encoding header of request.
+func encodingQuality(r *http.Request, encoding string) float64 {
+	encoding = strings.ToLower(encoding)
+	if r == nil {
+		return identityQuality(encoding)
+	}
+	wildcard := -1.0
+	for _, spec := range parseQualityValues(r.Header.Get("Accept-Encoding")) {
+		if spec.value == encoding {
+			return spec.q
+		}
+		if spec.value == "*" {
+			wildcard = spec.q
+		}
+	}
+	if wildcard >= 0 {
+		return wildcard
+	}
+	return identityQuality(encoding)
+}
+
+// identityQuality returns quality of coding that is not mentioned in
+// Accept-Encoding header.
+func identityQuality(encoding string) float64 {
+	if encoding == "identity" {
+		return 1
+	}
+	return 0
+}
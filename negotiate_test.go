@@ -0,0 +1,170 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func negotiate(url, accept string, offered ...string) string {
+	request, _ := http.NewRequest("GET", url, nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	return c.NegotiateFormat(offered...)
+}
+
+func TestNegotiateFormatQueryOverridesAccept(t *testing.T) {
+	format := negotiate("/users?format=xml", "application/json", FormatJSON, FormatXML)
+	if format != FormatXML {
+		t.Fatal("Query parameter did not override Accept header, got: ", format)
+	}
+}
+
+func TestNegotiateFormatExtension(t *testing.T) {
+	format := negotiate("/users.xml", "application/json", FormatJSON, FormatXML)
+	if format != FormatXML {
+		t.Fatal("Extension did not override Accept header, got: ", format)
+	}
+	format = negotiate("/users.xml?format=json", "", FormatJSON, FormatXML)
+	if format != FormatJSON {
+		t.Fatal("Query parameter did not override extension, got: ", format)
+	}
+}
+
+func TestNegotiateFormatAccept(t *testing.T) {
+	cases := []struct {
+		accept   string
+		expected string
+	}{
+		{"application/json", FormatJSON},
+		{"text/xml", FormatXML},
+		{"application/json;q=0.5, application/xml", FormatXML},
+		{"text/*", FormatXML},
+		{"*/*", FormatJSON},
+		{"", FormatJSON},
+		{"image/png", ""},
+		{"application/json;q=0", ""},
+	}
+	for _, tc := range cases {
+		if format := negotiate("/users", tc.accept, FormatJSON, FormatXML); format != tc.expected {
+			t.Fatalf("Expected %q for Accept %q, got: %q", tc.expected, tc.accept, format)
+		}
+	}
+}
+
+func TestNegotiateFormatNotOffered(t *testing.T) {
+	if format := negotiate("/users?format=html", "", FormatJSON); format != "" {
+		t.Fatal("Format that is not offered returned: ", format)
+	}
+}
+
+func TestNegotiateFormatWithoutRequest(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if format := c.NegotiateFormat(FormatXML, FormatJSON); format != FormatXML {
+		t.Fatal("First offered format not returned without request: ", format)
+	}
+}
+
+func acceptsEncoding(header, encoding string) bool {
+	request, _ := http.NewRequest("GET", "/", nil)
+	if header != "" {
+		request.Header.Set("Accept-Encoding", header)
+	}
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	return c.AcceptsEncoding(encoding)
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	if !acceptsEncoding("gzip, deflate, br", "gzip") {
+		t.Fatal("Gzip not accepted.")
+	}
+	if !acceptsEncoding("br;q=1.0, GZIP;q=0.5", "gzip") {
+		t.Fatal("Gzip with lower quality not accepted.")
+	}
+	if !acceptsEncoding("br, *;q=0.1", "gzip") {
+		t.Fatal("Gzip not accepted through wildcard.")
+	}
+	if !acceptsEncoding("gzip", "identity") || !acceptsEncoding("", "identity") {
+		t.Fatal("Identity not accepted by default.")
+	}
+	if acceptsEncoding("", "gzip") {
+		t.Fatal("Gzip accepted without Accept-Encoding header.")
+	}
+}
+
+func TestAcceptsEncodingDisabled(t *testing.T) {
+	if acceptsEncoding("gzip;q=0, br", "gzip") {
+		t.Fatal("Gzip disabled with quality 0 accepted.")
+	}
+	if acceptsEncoding("br, *;q=0", "gzip") {
+		t.Fatal("Gzip disabled by wildcard accepted.")
+	}
+	if acceptsEncoding("gzip, identity;q=0", "identity") {
+		t.Fatal("Identity disabled with quality 0 accepted.")
+	}
+	if acceptsEncoding("gzip, *;q=0", "identity") {
+		t.Fatal("Identity disabled by wildcard accepted.")
+	}
+}
+
+func requireAccept(accept string) (int, bool) {
+	var called bool
+	m := New(RequireAccept("application/json", "application/xml"))
+	m.UseFunc(func(c *Context) {
+		called = true
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response.Code, called
+}
+
+func TestRequireAccept(t *testing.T) {
+	if status, called := requireAccept("text/html, application/json;q=0.9"); status != http.StatusOK || !called {
+		t.Fatal("Matching Accept header rejected, status: ", status)
+	}
+	for _, accept := range []string{"*/*", "application/*", "text/html, */*;q=0.1"} {
+		if status, called := requireAccept(accept); status != http.StatusOK || !called {
+			t.Fatal("Wildcard ", accept, " rejected, status: ", status)
+		}
+	}
+}
+
+func TestRequireAcceptNotAcceptable(t *testing.T) {
+	for _, accept := range []string{"text/html", "application/json;q=0", ""} {
+		if status, called := requireAccept(accept); status != http.StatusNotAcceptable || called {
+			t.Fatal("Accept header ", accept, " not rejected, status: ", status)
+		}
+	}
+}
+
+func TestPreferredLanguage(t *testing.T) {
+	supported := []string{"en", "de", "pt-BR", "pt-PT"}
+	tests := map[string]string{
+		"de":                     "de",
+		"fr, de;q=0.8, en;q=0.9": "en",
+		"en-US,en;q=0.9":         "en",
+		"pt-pt, pt;q=0.9":        "pt-PT",
+		"pt":                     "pt-BR",
+		"fr, *;q=0.1":            "en",
+		"fr, ja":                 "en",
+		"":                       "en",
+		"de;q=0, en-GB;q=0.5":    "en",
+		"DE-at":                  "de",
+	}
+	for header, expected := range tests {
+		request, _ := http.NewRequest("GET", "/", nil)
+		if header != "" {
+			request.Header.Set("Accept-Language", header)
+		}
+		c := newContext(httptest.NewRecorder(), request, nil, nil)
+		if language := c.PreferredLanguage(supported...); language != expected {
+			t.Fatal("Wrong language for ", header, ": ", language)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mezvaro
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PaginationDefaults configures parsing of pagination parameters done by
+// Pagination method.
+type PaginationDefaults struct {
+	// PageSize is used when request does not specify page size. If 0,
+	// 20 is used.
+	PageSize int
+	// MaxPageSize is maximal allowed page size. If 0, page size is not
+	// limited.
+	MaxPageSize int
+	// Clamp controls handling of values out of allowed range. If true,
+	// they are clamped to nearest allowed value, otherwise error is
+	// returned.
+	Clamp bool
+}
+
+// defaultPageSize is page size used when neither request nor defaults
+// specify it.
+const defaultPageSize = 20
+
+// Pagination parses pagination parameters from query of request. Page is
+// read from "page" parameter (starting at 1) and page size from "page_size"
+// parameter. Alternatively, "limit" and "offset" parameters are supported,
+// in which case limit is used as page size and page is one that starts at
+// provided offset. Offset has to be multiple of limit, since other offsets
+// can not be expressed as page, so error is returned for them regardless of
+// clamping. Missing parameters are set to defaults. Values
+// that are not numbers result in error, and values out of range are either
+// clamped or result in error, depending on defaults. Returned errors are
+// *BindError.
+func (c *Context) Pagination(defaults PaginationDefaults) (page, pageSize int, err error) {
+	if defaults.PageSize <= 0 {
+		defaults.PageSize = defaultPageSize
+	}
+	if defaults.MaxPageSize > 0 && defaults.PageSize > defaults.MaxPageSize {
+		defaults.PageSize = defaults.MaxPageSize
+	}
+	query := c.Request.URL.Query()
+	sizeParam := "page_size"
+	if query.Has("limit") || query.Has("offset") {
+		sizeParam = "limit"
+	}
+
+	pageSize, err = queryInt(query.Get(sizeParam), sizeParam, defaults.PageSize)
+	if err != nil {
+		return 0, 0, err
+	}
+	switch {
+	case pageSize < 1:
+		err = paginationRangeError(sizeParam, "has to be positive", defaults.Clamp)
+		pageSize = 1
+	case defaults.MaxPageSize > 0 && pageSize > defaults.MaxPageSize:
+		err = paginationRangeError(sizeParam, fmt.Sprintf("can not be greater than %d", defaults.MaxPageSize), defaults.Clamp)
+		pageSize = defaults.MaxPageSize
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if sizeParam == "limit" {
+		offset, err := queryInt(query.Get("offset"), "offset", 0)
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset < 0 {
+			if err := paginationRangeError("offset", "can not be negative", defaults.Clamp); err != nil {
+				return 0, 0, err
+			}
+			offset = 0
+		}
+		if offset%pageSize != 0 {
+			return 0, 0, &BindError{Format: "query", Message: fmt.Sprintf(`"offset" has to be multiple of %q`, sizeParam)}
+		}
+		return offset/pageSize + 1, pageSize, nil
+	}
+
+	page, err = queryInt(query.Get("page"), "page", 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	if page < 1 {
+		if err := paginationRangeError("page", "has to be positive", defaults.Clamp); err != nil {
+			return 0, 0, err
+		}
+		page = 1
+	}
+	return page, pageSize, nil
+}
+
+// queryInt parses integer query parameter, returning default if it is empty.
+func queryInt(value, name string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, &BindError{Format: "query", Message: fmt.Sprintf("invalid value for %q", name), Err: err}
+	}
+	return n, nil
+}
+
+// paginationRangeError returns error for pagination parameter out of range,
+// or nil if values are clamped.
+func paginationRangeError(name, message string, clamp bool) error {
+	if clamp {
+		return nil
+	}
+	return &BindError{Format: "query", Message: fmt.Sprintf("%q %s", name, message)}
+}
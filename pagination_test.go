@@ -0,0 +1,74 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func paginate(url string, defaults PaginationDefaults) (int, int, error) {
+	request, _ := http.NewRequest("GET", url, nil)
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	return c.Pagination(defaults)
+}
+
+func TestPaginationDefaults(t *testing.T) {
+	page, pageSize, err := paginate("/items", PaginationDefaults{})
+	if err != nil || page != 1 || pageSize != 20 {
+		t.Fatal("Wrong defaults: ", page, pageSize, err)
+	}
+	page, pageSize, err = paginate("/items", PaginationDefaults{PageSize: 50})
+	if err != nil || page != 1 || pageSize != 50 {
+		t.Fatal("Configured default page size not used: ", page, pageSize, err)
+	}
+}
+
+func TestPaginationProvided(t *testing.T) {
+	page, pageSize, err := paginate("/items?page=3&page_size=10", PaginationDefaults{})
+	if err != nil || page != 3 || pageSize != 10 {
+		t.Fatal("Wrong pagination: ", page, pageSize, err)
+	}
+	page, pageSize, err = paginate("/items?limit=10&offset=20", PaginationDefaults{})
+	if err != nil || page != 3 || pageSize != 10 {
+		t.Fatal("Wrong pagination from limit and offset: ", page, pageSize, err)
+	}
+}
+
+func TestPaginationClamp(t *testing.T) {
+	defaults := PaginationDefaults{MaxPageSize: 100, Clamp: true}
+	page, pageSize, err := paginate("/items?page=0&page_size=1000", defaults)
+	if err != nil || page != 1 || pageSize != 100 {
+		t.Fatal("Values not clamped: ", page, pageSize, err)
+	}
+	page, pageSize, err = paginate("/items?page=-2&page_size=-5", defaults)
+	if err != nil || page != 1 || pageSize != 1 {
+		t.Fatal("Negative values not clamped: ", page, pageSize, err)
+	}
+}
+
+func TestPaginationOutOfRange(t *testing.T) {
+	var bindErr *BindError
+	_, _, err := paginate("/items?page_size=1000", PaginationDefaults{MaxPageSize: 100})
+	if !errors.As(err, &bindErr) {
+		t.Fatal("Expected bind error for page size out of range, got: ", err)
+	}
+	_, _, err = paginate("/items?page=0", PaginationDefaults{})
+	if !errors.As(err, &bindErr) {
+		t.Fatal("Expected bind error for page out of range, got: ", err)
+	}
+	_, _, err = paginate("/items?page=first", PaginationDefaults{Clamp: true})
+	if !errors.As(err, &bindErr) {
+		t.Fatal("Expected bind error for invalid number, got: ", err)
+	}
+}
+
+func TestPaginationUnalignedOffset(t *testing.T) {
+	for _, defaults := range []PaginationDefaults{{}, {Clamp: true}} {
+		var bindErr *BindError
+		_, _, err := paginate("/items?limit=10&offset=5", defaults)
+		if !errors.As(err, &bindErr) {
+			t.Fatal("Expected bind error for offset not aligned with limit, got: ", err)
+		}
+	}
+}
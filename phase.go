@@ -0,0 +1,77 @@
+package mezvaro
+
+// Phase is stage of processing of request. Handlers registered for phases
+// with UsePhase are executed grouped by phases, in order phases are defined,
+// regardless of order in which they are registered.
+type Phase int
+
+// Phases of processing of request, in order they are executed.
+const (
+	// PhaseAuth is for authentication and authorization handlers.
+	PhaseAuth Phase = iota
+	// PhasePreHandler is for handlers that prepare request, like binding
+	// or validation.
+	PhasePreHandler
+	// PhaseHandler is phase of handlers added with Use and of handlers
+	// created with H.
+	PhaseHandler
+	// PhasePostHandler is for handlers that are executed after request has
+	// been handled, like auditing, unless chain is aborted.
+	PhasePostHandler
+)
+
+// UsePhase adds handlers to used instance of Mezvaro for provided phase.
+// Chain executes handlers of all phases in order of phases, and handlers of
+// the same phase in order they are added, with handlers of parents before
+// handlers of forks. Adding handlers for PhaseHandler is the same as adding
+// them with Use. This gives structure to chains of large applications, where
+// handlers are registered from different places:
+//
+//	m.UsePhase(mezvaro.PhasePostHandler, audit)
+//	m.UsePhase(mezvaro.PhaseAuth, authenticate) // executed before audit
+//
+// It panics if any of handlers is nil or if phase is unknown.
+func (m *Mezvaro) UsePhase(phase Phase, handlers ...Handler) *Mezvaro {
+	if phase < PhaseAuth || phase > PhasePostHandler {
+		panic("mezvaro: unknown phase")
+	}
+	if phase == PhaseHandler {
+		return m.Use(handlers...)
+	}
+	checkHandlers(handlers)
+	if m.phaseHandlers == nil {
+		m.phaseHandlers = make(map[Phase][]Handler)
+	}
+	m.phaseHandlers[phase] = append(m.phaseHandlers[phase], handlers...)
+	m.version.Add(1)
+	return m
+}
+
+// withPhases returns chain of handlers with provided handlers of
+// PhaseHandler and handlers of other phases registered with this instance and
+// its parents, ordered by phases.
+func (m *Mezvaro) withPhases(handlers []Handler) []Handler {
+	var levels []*Mezvaro
+	phased := 0
+	for current := m; current != nil; current = current.chainParent() {
+		levels = append(levels, current)
+		for _, phaseHandlers := range current.phaseHandlers {
+			phased += len(phaseHandlers)
+		}
+	}
+	if phased == 0 {
+		return handlers
+	}
+	chain := make([]Handler, 0, len(handlers)+phased)
+	for phase := PhaseAuth; phase <= PhasePostHandler; phase++ {
+		if phase == PhaseHandler {
+			chain = append(chain, handlers...)
+			continue
+		}
+		// parents are at the end of levels
+		for i := len(levels) - 1; i >= 0; i-- {
+			chain = append(chain, levels[i].phaseHandlers[phase]...)
+		}
+	}
+	return chain
+}
@@ -0,0 +1,53 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUsePhase(t *testing.T) {
+	var calls []string
+	record := func(name string) Handler {
+		return HandlerFunc(func(c *Context) { calls = append(calls, name) })
+	}
+	m := New(record("handler"))
+	m.UsePhase(PhasePostHandler, record("post"))
+	m.UsePhase(PhaseAuth, record("auth"))
+	m.UsePhase(PhasePreHandler, record("pre"))
+	fork := m.Fork(record("fork-handler"))
+	fork.UsePhase(PhaseAuth, record("fork-auth"))
+	fork.UsePhase(PhaseHandler, record("fork-use-phase"))
+
+	fork.ServeHTTP(httptest.NewRecorder(), nil)
+	expected := "auth fork-auth pre handler fork-handler fork-use-phase post"
+	if strings.Join(calls, " ") != expected {
+		t.Fatal("Handlers not grouped by phase: ", calls)
+	}
+
+	calls = nil
+	fork.HF(func(c *Context) { calls = append(calls, "final") }).ServeHTTP(httptest.NewRecorder(), nil)
+	expected = "auth fork-auth pre handler fork-handler fork-use-phase final post"
+	if strings.Join(calls, " ") != expected {
+		t.Fatal("Handler created with H not in handler phase: ", calls)
+	}
+}
+
+func TestUsePhaseAbort(t *testing.T) {
+	var called bool
+	m := New(HandlerFunc(func(c *Context) { called = true }))
+	m.UsePhase(PhaseAuth, HandlerFunc(func(c *Context) { c.Abort() }))
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if called {
+		t.Fatal("Handler called after auth phase aborted chain.")
+	}
+}
+
+func TestUsePhaseUnknown(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Unknown phase accepted.")
+		}
+	}()
+	New().UsePhase(Phase(42), HandlerFunc(func(c *Context) {}))
+}
@@ -0,0 +1,113 @@
+package mezvaro
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pprof returns handler that serves runtime profiling data in format expected
+// by pprof tool, like net/http/pprof does. Unlike importing net/http/pprof,
+// nothing is registered on http.DefaultServeMux, so profiling endpoints are
+// exposed only where handler is mounted and only through chain of handlers
+// before it, which can protect them with authentication:
+//
+//	admin := mezvaro.New(AdminOnly)
+//	mux.Handle("/debug/pprof/", admin.H(mezvaro.Pprof()))
+//
+// Profile is selected by last element of URL path, so handler can be mounted
+// under any prefix. Empty name serves index of available profiles, "cmdline"
+// serves command line of process, "profile" serves CPU profile and "trace"
+// serves execution trace, both collected for duration given in "seconds" query
+// parameter (30 and 1 seconds by default). Other names serve profiles from
+// runtime/pprof, like "heap" or "goroutine", with "debug" and "gc" query
+// parameters handled like in net/http/pprof.
+func Pprof() Handler {
+	return HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("X-Content-Type-Options", "nosniff")
+		name := ""
+		if p := c.Request.URL.Path; !strings.HasSuffix(p, "/") {
+			name = path.Base(p)
+		}
+		switch name {
+		case "":
+			pprofIndex(c)
+		case "cmdline":
+			c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(c.Response, strings.Join(os.Args, "\x00"))
+		case "profile":
+			pprofCollect(c, 30, "CPU profile", pprof.StartCPUProfile, pprof.StopCPUProfile)
+		case "trace":
+			pprofCollect(c, 1, "execution trace", trace.Start, trace.Stop)
+		default:
+			pprofLookup(c, name)
+		}
+	})
+}
+
+// pprofIndex lists available profiles.
+func pprofIndex(c *Context) {
+	c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	var b strings.Builder
+	b.WriteString("<html><head><title>profiles</title></head><body><ul>\n")
+	for _, p := range pprof.Profiles() {
+		name := html.EscapeString(p.Name())
+		fmt.Fprintf(&b, "<li><a href=\"%s?debug=1\">%s</a> (%d)</li>\n", name, name, p.Count())
+	}
+	b.WriteString("<li><a href=\"profile\">profile</a></li>\n<li><a href=\"trace\">trace</a></li>\n")
+	b.WriteString("</ul></body></html>\n")
+	c.Response.Write([]byte(b.String()))
+}
+
+// pprofCollect collects profile for duration from query of request and writes
+// it to response. Collection is stopped early if context is done.
+func pprofCollect(c *Context, defaultSeconds int, what string, start func(io.Writer) error, stop func()) {
+	seconds, err := strconv.Atoi(c.Request.URL.Query().Get("seconds"))
+	if err != nil || seconds <= 0 {
+		seconds = defaultSeconds
+	}
+	c.Response.Header().Set("Content-Type", "application/octet-stream")
+	c.Response.Header().Set("Content-Disposition", `attachment; filename="`+path.Base(c.Request.URL.Path)+`"`)
+	if err := start(c.Response); err != nil {
+		c.Response.Header().Del("Content-Disposition")
+		c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("mezvaro: could not start %s: %v", what, err))
+		return
+	}
+	timer := time.NewTimer(time.Duration(seconds) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-c.Done():
+	}
+	stop()
+}
+
+// pprofLookup writes profile with provided name.
+func pprofLookup(c *Context, name string) {
+	profile := pprof.Lookup(name)
+	if profile == nil {
+		http.Error(c.Response, "unknown profile", http.StatusNotFound)
+		return
+	}
+	query := c.Request.URL.Query()
+	if name == "heap" && query.Get("gc") != "" {
+		runtime.GC()
+	}
+	debug, _ := strconv.Atoi(query.Get("debug"))
+	if debug > 0 {
+		c.Response.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		c.Response.Header().Set("Content-Type", "application/octet-stream")
+		c.Response.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	profile.WriteTo(c.Response, debug)
+}
@@ -0,0 +1,61 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func pprofServer() http.Handler {
+	admin := New(HandlerFunc(func(c *Context) {
+		if c.Request.Header.Get("X-Admin") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}))
+	mux := http.NewServeMux()
+	mux.Handle("/debug/pprof/", admin.H(Pprof()))
+	return mux
+}
+
+func TestPprof(t *testing.T) {
+	request := httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil)
+	request.Header.Set("X-Admin", "yes")
+	response := httptest.NewRecorder()
+	pprofServer().ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected 200, got: ", response.Code)
+	}
+	if !strings.Contains(response.Body.String(), "goroutine profile") {
+		t.Fatal("Goroutine profile not served: ", response.Body.String())
+	}
+
+	request = httptest.NewRequest("GET", "/debug/pprof/", nil)
+	request.Header.Set("X-Admin", "yes")
+	response = httptest.NewRecorder()
+	pprofServer().ServeHTTP(response, request)
+	if !strings.Contains(response.Body.String(), "heap") {
+		t.Fatal("Index does not list profiles: ", response.Body.String())
+	}
+}
+
+func TestPprofProtected(t *testing.T) {
+	request := httptest.NewRequest("GET", "/debug/pprof/goroutine?debug=1", nil)
+	response := httptest.NewRecorder()
+	pprofServer().ServeHTTP(response, request)
+	if response.Code != http.StatusUnauthorized {
+		t.Fatal("Profile served without passing chain, status: ", response.Code)
+	}
+}
+
+func TestPprofUnknownProfile(t *testing.T) {
+	request := httptest.NewRequest("GET", "/debug/pprof/unknown", nil)
+	request.Header.Set("X-Admin", "yes")
+	response := httptest.NewRecorder()
+	pprofServer().ServeHTTP(response, request)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for unknown profile, got: ", response.Code)
+	}
+}
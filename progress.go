@@ -0,0 +1,60 @@
+package mezvaro
+
+import "sync"
+
+// WithProgress enables progress reporting for long running handlers, like
+// uploads and exports. Returned report function publishes current progress
+// and it is safe to call it from worker goroutine. Returned done function
+// has to be called when work is finished, after which reports are ignored.
+//
+// Published values can be consumed from channel returned by Progress, usually
+// by streaming code that forwards them to client. Progress is not queued,
+// if consumer is slower then worker only latest value is kept, but the last
+// reported value is always delivered before channel is closed.
+func (c *Context) WithProgress() (report func(float64), done func()) {
+	ch := make(chan float64, 1)
+	c.mu.Lock()
+	c.progress = ch
+	c.mu.Unlock()
+
+	var (
+		mu     sync.Mutex
+		closed bool
+	)
+	report = func(value float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- value:
+		default:
+			// consumer did not pick up previous value yet, replace it
+			// with newer one
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- value
+		}
+	}
+	done = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !closed {
+			closed = true
+			close(ch)
+		}
+	}
+	return report, done
+}
+
+// Progress returns channel on which progress reported through function
+// returned by WithProgress is published. Channel is closed when done function
+// is called. If WithProgress was not called, nil is returned.
+func (c *Context) Progress() <-chan float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.progress
+}
@@ -0,0 +1,59 @@
+package mezvaro
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		report, done := c.WithProgress()
+		go func() {
+			defer done()
+			for i := 1; i <= 10; i++ {
+				report(float64(i) / 10)
+			}
+		}()
+		for p := range c.Progress() {
+			fmt.Fprintf(c.Response, "%.1f\n", p)
+		}
+	}))
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(response, request)
+
+	lines := strings.Split(strings.TrimSpace(response.Body.String()), "\n")
+	if len(lines) == 0 {
+		t.Fatal("No progress forwarded to response.")
+	}
+	if lines[len(lines)-1] != "1.0" {
+		t.Fatal("Last progress value not delivered, got: ", lines[len(lines)-1])
+	}
+	for i := 1; i < len(lines); i++ {
+		if lines[i] <= lines[i-1] {
+			t.Fatal("Progress values delivered out of order: ", lines)
+		}
+	}
+}
+
+func TestProgressReportAfterDone(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	report, done := c.WithProgress()
+	done()
+	// must not panic
+	report(0.5)
+	done()
+	if _, ok := <-c.Progress(); ok {
+		t.Fatal("Progress channel not closed.")
+	}
+}
+
+func TestProgressNotEnabled(t *testing.T) {
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if c.Progress() != nil {
+		t.Fatal("Expected nil progress channel.")
+	}
+}
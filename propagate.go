@@ -0,0 +1,49 @@
+package mezvaro
+
+import (
+	"io"
+	"net/http"
+)
+
+// Propagate returns middleware that stores values of provided headers of
+// request in context, so they are copied to outbound requests created with
+// NewOutboundRequest. This propagates headers like X-Tenant-ID or
+// X-Correlation-ID across services. Headers missing in request are not
+// propagated. Multiple Propagate middlewares in chain add to headers
+// propagated by previous ones.
+func Propagate(headers ...string) Handler {
+	return HandlerFunc(func(c *Context) {
+		propagated := c.PropagatedHeaders()
+		for _, name := range headers {
+			if values := c.Request.Header.Values(name); len(values) > 0 {
+				propagated[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+			}
+		}
+		propagatedHeadersKey.Set(c, propagated)
+		c.Next()
+	})
+}
+
+// PropagatedHeaders returns copy of headers stored by Propagate middleware.
+// If there are none, empty header is returned.
+func (c *Context) PropagatedHeaders() http.Header {
+	if headers, ok := propagatedHeadersKey.Get(c); ok {
+		return headers.Clone()
+	}
+	return http.Header{}
+}
+
+// NewOutboundRequest creates request to other service, like
+// http.NewRequestWithContext does, with context of request, so deadline and
+// cancellation propagate to outbound request as well. Headers stored by
+// Propagate middleware are set on created request.
+func (c *Context) NewOutboundRequest(method, url string, body io.Reader) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(c.Ctx(), method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range c.PropagatedHeaders() {
+		r.Header[name] = values
+	}
+	return r, nil
+}
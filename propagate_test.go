@@ -0,0 +1,40 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPropagate(t *testing.T) {
+	var outbound *http.Request
+	m := New(Propagate("x-tenant-id", "X-Missing"), Propagate("X-Correlation-ID"))
+	m.UseFunc(func(c *Context) {
+		cancel := c.WithTimeout(time.Minute)
+		defer cancel()
+		var err error
+		outbound, err = c.NewOutboundRequest("GET", "http://backend/users", nil)
+		if err != nil {
+			t.Fatal("Creating outbound request failed: ", err)
+		}
+		if c.PropagatedHeaders().Get("X-Tenant-ID") != "acme" {
+			t.Fatal("Propagated headers not stored in context.")
+		}
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("X-Tenant-ID", "acme")
+	request.Header.Set("X-Correlation-ID", "abc-123")
+	request.Header.Set("Authorization", "secret")
+	m.ServeHTTP(httptest.NewRecorder(), request)
+
+	if outbound.Header.Get("X-Tenant-ID") != "acme" || outbound.Header.Get("X-Correlation-ID") != "abc-123" {
+		t.Fatal("Headers not propagated: ", outbound.Header)
+	}
+	if _, ok := outbound.Header["X-Missing"]; ok || outbound.Header.Get("Authorization") != "" {
+		t.Fatal("Unexpected headers propagated: ", outbound.Header)
+	}
+	if _, ok := outbound.Context().Deadline(); !ok {
+		t.Fatal("Request context not used for outbound request.")
+	}
+}
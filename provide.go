@@ -0,0 +1,65 @@
+package mezvaro
+
+import "sync"
+
+// Provide registers factory for request scoped instances under provided key,
+// for example database transactions or repositories. Instances are created
+// lazily, when they are first resolved with Context.Resolve, and same
+// instance is returned for all resolutions during single request. Forks
+// inherit providers of their parents and can override them.
+func (m *Mezvaro) Provide(key interface{}, factory func() interface{}) *Mezvaro {
+	if m.providers == nil {
+		m.providers = make(map[interface{}]func() interface{})
+	}
+	m.providers[key] = factory
+	return m
+}
+
+// provider returns factory registered under key on this instance or its
+// closest parent.
+func (m *Mezvaro) provider(key interface{}) func() interface{} {
+	for current := m; current != nil; current = current.parent {
+		if factory, ok := current.providers[key]; ok {
+			return factory
+		}
+	}
+	return nil
+}
+
+// resolvedInstance holds instance created by provider during request.
+type resolvedInstance struct {
+	once    sync.Once
+	factory func() interface{}
+	value   interface{}
+}
+
+// Resolve returns request scoped instance registered under provided key with
+// Mezvaro.Provide. Instance is created on first call and cached for the rest
+// of request. It is safe to call Resolve concurrently. If there is no provider
+// for key, nil is returned.
+func (c *Context) Resolve(key interface{}) interface{} {
+	c.mu.Lock()
+	instance, ok := c.resolved[key]
+	if !ok {
+		var factory func() interface{}
+		if c.mezvaro != nil {
+			factory = c.mezvaro.provider(key)
+		}
+		if factory == nil {
+			c.mu.Unlock()
+			return nil
+		}
+		if c.resolved == nil {
+			c.resolved = make(map[interface{}]*resolvedInstance)
+		}
+		instance = &resolvedInstance{factory: factory}
+		c.resolved[key] = instance
+	}
+	c.mu.Unlock()
+	// factory is called outside of lock, so it can resolve other instances
+	// it depends on, while concurrent callers wait for it to finish
+	instance.once.Do(func() {
+		instance.value = instance.factory()
+	})
+	return instance.value
+}
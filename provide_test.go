@@ -0,0 +1,88 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type repository struct {
+	id int64
+}
+
+func TestResolve(t *testing.T) {
+	var created int64
+	m := New().Provide("repo", func() interface{} {
+		return &repository{id: atomic.AddInt64(&created, 1)}
+	})
+	var first, second interface{}
+	m.UseFunc(func(c *Context) {
+		first = c.Resolve("repo")
+		c.Next()
+	}, func(c *Context) {
+		second = c.Resolve("repo")
+	})
+
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if created != 1 {
+		t.Fatal("Expected provider to be called once, called: ", created)
+	}
+	if first != second {
+		t.Fatal("Different instances resolved during same request.")
+	}
+
+	previous := first
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if created != 2 {
+		t.Fatal("Instance not created for new request.")
+	}
+	if first == previous {
+		t.Fatal("Instance reused between requests.")
+	}
+}
+
+func TestResolveConcurrent(t *testing.T) {
+	var created int64
+	m := New().Provide("repo", func() interface{} {
+		return &repository{id: atomic.AddInt64(&created, 1)}
+	})
+	results := make([]interface{}, 10)
+	m.UseFunc(func(c *Context) {
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = c.Resolve("repo")
+			}(i)
+		}
+		wg.Wait()
+	})
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if created != 1 {
+		t.Fatal("Expected provider to be called once, called: ", created)
+	}
+	for _, r := range results {
+		if r != results[0] {
+			t.Fatal("Different instances resolved concurrently.")
+		}
+	}
+}
+
+func TestResolveInheritedAndMissing(t *testing.T) {
+	var inherited, missing interface{}
+	m := New().Provide("repo", func() interface{} {
+		return &repository{}
+	})
+	m.Fork().UseFunc(func(c *Context) {
+		inherited = c.Resolve("repo")
+		missing = c.Resolve("missing")
+	}).ServeHTTP(httptest.NewRecorder(), nil)
+	if _, ok := inherited.(*repository); !ok {
+		t.Fatal("Provider of parent not used by fork.")
+	}
+	if missing != nil {
+		t.Fatal("Expected nil for key without provider.")
+	}
+}
@@ -0,0 +1,55 @@
+package mezvaro
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Recovery returns middleware that recovers from panics in rest of the chain,
+// logs them with stack trace to provided logger (slog.Default if nil) and
+// aborts chain.
+//
+// If response has not been written yet, client receives response with 500
+// Internal Server Error status. If panic occurred after part of response had
+// already been written, status and headers can not be changed anymore, so
+// nothing else is written and connection is reset instead, by setting write
+// deadline in the past, so client does not interpret partial response as
+// successful one.
+//
+// Panics with http.ErrAbortHandler are not recovered, since they are used
+// for aborting response intentionally.
+func Recovery(logger *slog.Logger) Handler {
+	return HandlerFunc(func(c *Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+			log := logger
+			if log == nil {
+				log = slog.Default()
+			}
+			written := c.Written()
+			log.LogAttrs(c, slog.LevelError, "panic recovered",
+				slog.Any("panic", recovered),
+				slog.Bool("written", written),
+				slog.String("stack", string(debug.Stack())),
+			)
+			err := fmt.Errorf("mezvaro: panic: %v", recovered)
+			if !written {
+				c.AbortWithError(http.StatusInternalServerError, err)
+				return
+			}
+			c.Error(err)
+			http.NewResponseController(c.Response).SetWriteDeadline(time.Unix(1, 0))
+			c.Abort()
+		}()
+		c.Next()
+	})
+}
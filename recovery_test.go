@@ -0,0 +1,71 @@
+package mezvaro
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRecovery(t *testing.T) {
+	var buf bytes.Buffer
+	m := New(Recovery(slog.New(slog.NewTextHandler(&buf, nil))))
+	m.UseFunc(func(c *Context) {
+		panic("handler failed")
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Expected 500 after panic, got: ", response.Code)
+	}
+	if !strings.Contains(buf.String(), "handler failed") {
+		t.Fatal("Panic not logged: ", buf.String())
+	}
+}
+
+func TestRecoveryAfterPartialWrite(t *testing.T) {
+	var buf bytes.Buffer
+	var errs []error
+	m := New(HandlerFunc(func(c *Context) {
+		c.Next()
+		errs = c.Errors()
+	}), Recovery(slog.New(slog.NewTextHandler(&buf, nil))))
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte("partial"))
+		panic("handler failed")
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if response.Code != http.StatusOK || response.Body.String() != "partial" {
+		t.Fatal("Partially written response modified: ", response.Code, response.Body.String())
+	}
+	if !strings.Contains(buf.String(), "written=true") {
+		t.Fatal("Panic after partial write not logged: ", buf.String())
+	}
+	if len(errs) != 1 {
+		t.Fatal("Panic not recorded as error: ", errs)
+	}
+}
+
+func TestRecoveryResetsConnection(t *testing.T) {
+	m := New(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	m.UseFunc(func(c *Context) {
+		c.Response.Write([]byte("partial"))
+		c.Response.(http.Flusher).Flush()
+		panic("handler failed")
+	})
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal("Request failed: ", err)
+	}
+	defer response.Body.Close()
+	if _, err := io.ReadAll(response.Body); err == nil {
+		t.Fatal("Partial response received as complete.")
+	}
+}
@@ -0,0 +1,213 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+)
+
+// ErrInvalidCallback is returned by JSONP when callback name is not valid
+// JavaScript identifier.
+var ErrInvalidCallback = errors.New("mezvaro: invalid JSONP callback name")
+
+// callbackPattern matches JavaScript identifiers, optionally separated by
+// dots, that are allowed as JSONP callback names.
+var callbackPattern = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$]*(\.[a-zA-Z_$][a-zA-Z0-9_$]*)*$`)
+
+// maxCallbackLength is maximal length of JSONP callback name.
+const maxCallbackLength = 128
+
+// NilJSON determines how JSON method responds when value is nil.
+type NilJSON int
+
+// Ways of responding with nil value, configured with WithNilJSON.
+const (
+	// NilJSONNull writes null, which is how nil is encoded as JSON. This
+	// is default.
+	NilJSONNull NilJSON = iota
+	// NilJSONEmptyObject writes empty object ({}) instead.
+	NilJSONEmptyObject
+	// NilJSONEmptyBody writes only status, without body.
+	NilJSONEmptyBody
+)
+
+// WithNilJSON sets how JSON method responds with nil value for Mezvaro
+// instance and its forks. Some clients reject null as response body, so it
+// can be replaced with empty object or empty body.
+func WithNilJSON(mode NilJSON) Option {
+	return func(m *Mezvaro) {
+		m.nilJSON = mode
+	}
+}
+
+// nilJSONMode returns how nil JSON values are written for this instance or
+// its closest parent that configures it.
+func (m *Mezvaro) nilJSONMode() NilJSON {
+	for current := m; current != nil; current = current.parent {
+		if current.nilJSON != NilJSONNull {
+			return current.nilJSON
+		}
+	}
+	return NilJSONNull
+}
+
+// JSON responds with provided status and v encoded as JSON. If v is nil,
+// including nil pointer, map and slice, which are encoded as null too, it is
+// written as configured with WithNilJSON, by default as null.
+//
+// If v can not be encoded and response has not been written yet, response
+// with 500 Internal Server Error status is written instead. If response has
+// already been written, status and headers are left as they are. In both
+// cases error is recorded with Error method and returned.
+func (c *Context) JSON(status int, v interface{}) error {
+	if isNil(v) {
+		switch c.mezvaro.nilJSONMode() {
+		case NilJSONEmptyObject:
+			return c.render(status, "application/json; charset=utf-8", []byte("{}\n"))
+		case NilJSONEmptyBody:
+			if !c.Written() {
+				c.Response.WriteHeader(status)
+			}
+			return nil
+		}
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return c.renderFailed(err)
+	}
+	return c.render(status, "application/json; charset=utf-8", append(body, '\n'))
+}
+
+// isNil checks if v is nil or nil value of pointer, map or slice, which are
+// all encoded as null.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch value := reflect.ValueOf(v); value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// JSONP responds with provided status and v encoded as JSON wrapped in call
+// of JavaScript function with provided callback name, for legacy cross-origin
+// clients. Callback name has to be JavaScript identifier (dot separated names
+// are allowed), otherwise ErrInvalidCallback is returned and nothing is
+// written, which prevents script injection through callback name. Encoding
+// errors are handled same as in JSON.
+func (c *Context) JSONP(status int, callback string, v interface{}) error {
+	if len(callback) > maxCallbackLength || !callbackPattern.MatchString(callback) {
+		return ErrInvalidCallback
+	}
+	body, err := json.Marshal(v)
+	if err != nil {
+		return c.renderFailed(err)
+	}
+	c.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	// leading comment prevents content from being interpreted as something
+	// else then JavaScript, for example as Flash file
+	script := make([]byte, 0, len(callback)+len(body)+8)
+	script = append(script, "/**/"+callback+"("...)
+	script = append(script, body...)
+	script = append(script, ");\n"...)
+	return c.render(status, "application/javascript; charset=utf-8", script)
+}
+
+// HTML responds with provided status and output of executing template with
+// data. Output is written as template is executed, so large pages are not
+// buffered, but because of that failure of template can not change status of
+// response once part of it has been written. Errors are handled same as in
+// JSON. If context is cancelled or its deadline expires during execution, for
+// example because client has disconnected, writing stops, execution is
+// aborted and context error is recorded with Error method and returned.
+func (c *Context) HTML(status int, tmpl *template.Template, data interface{}) error {
+	w := &templateWriter{c: c, status: status}
+	err := tmpl.Execute(w, data)
+	if err == nil {
+		w.start()
+		return nil
+	}
+	if ctxErr := c.Err(); ctxErr != nil && err == ctxErr {
+		c.Error(err)
+		return err
+	}
+	return c.renderFailed(err)
+}
+
+// templateWriter writes output of template to response, writing status and
+// content type before first write, and stops writing when context is done.
+type templateWriter struct {
+	c       *Context
+	status  int
+	started bool
+}
+
+// Write implements io.Writer interface.
+func (w *templateWriter) Write(b []byte) (int, error) {
+	if err := w.c.Err(); err != nil {
+		return 0, err
+	}
+	w.start()
+	return w.c.Response.Write(b)
+}
+
+// start writes status and content type, unless they have already been
+// written.
+func (w *templateWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if !w.c.Written() {
+		w.c.Response.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.c.Response.WriteHeader(w.status)
+	}
+}
+
+// render writes rendered body with provided status and content type. Status
+// and content type are written only if response has not been written yet.
+func (c *Context) render(status int, contentType string, body []byte) error {
+	if !c.Written() {
+		c.Response.Header().Set("Content-Type", contentType)
+		c.Response.WriteHeader(status)
+	}
+	if _, err := c.Response.Write(body); err != nil {
+		c.Error(err)
+		return err
+	}
+	return nil
+}
+
+// renderFailed handles error that occurred during rendering of response.
+func (c *Context) renderFailed(err error) error {
+	c.Error(err)
+	if !c.Written() {
+		c.renderError(http.StatusInternalServerError, err)
+	}
+	return err
+}
+
+// HandleJSON creates handler from function that returns value to respond
+// with. On success, value is encoded as JSON and written with 200 OK status,
+// otherwise error is passed to SendError, which determines status of
+// response. It is intended for API handlers, to avoid repeating encoding
+// and error handling in each of them:
+//
+//	m.H(mezvaro.HandleJSON(func(c *mezvaro.Context) (*User, error) {
+//		return users.Get(c.URLParam("id"))
+//	}))
+func HandleJSON[T any](fn func(*Context) (T, error)) Handler {
+	return HandlerFunc(func(c *Context) {
+		v, err := fn(c)
+		if err != nil {
+			c.SendError(err)
+			return
+		}
+		c.JSON(http.StatusOK, v)
+	})
+}
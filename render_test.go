@@ -0,0 +1,264 @@
+package mezvaro
+
+import (
+	"errors"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+)
+
+var errMarshal = errors.New("marshal failed")
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errMarshal
+}
+
+// failingWriter is response writer whose writes always fail.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (w failingWriter) Write(b []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestJSON(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.JSON(http.StatusCreated, map[string]string{"name": "mezvaro"})
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if response.Code != http.StatusCreated {
+		t.Fatal("Expected status 201, got: ", response.Code)
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if strings.TrimSpace(response.Body.String()) != `{"name":"mezvaro"}` {
+		t.Fatal("Wrong body: ", response.Body.String())
+	}
+}
+
+func TestJSONNil(t *testing.T) {
+	for mode, expected := range map[NilJSON]string{
+		NilJSONNull:        "null\n",
+		NilJSONEmptyObject: "{}\n",
+		NilJSONEmptyBody:   "",
+	} {
+		m := New().With(WithNilJSON(mode))
+		response := httptest.NewRecorder()
+		m.Fork().HF(func(c *Context) {
+			c.JSON(http.StatusOK, nil)
+		}).ServeHTTP(response, nil)
+		if response.Code != http.StatusOK {
+			t.Fatal("Wrong status for mode ", mode, ": ", response.Code)
+		}
+		if response.Body.String() != expected {
+			t.Fatal("Wrong body for mode ", mode, ": ", response.Body.String())
+		}
+	}
+}
+
+func TestJSONTypedNil(t *testing.T) {
+	type user struct{ Name string }
+	for _, v := range []interface{}{(*user)(nil), map[string]int(nil), []int(nil)} {
+		m := New().With(WithNilJSON(NilJSONEmptyObject))
+		response := httptest.NewRecorder()
+		m.Fork().HF(func(c *Context) {
+			c.JSON(http.StatusOK, v)
+		}).ServeHTTP(response, nil)
+		if response.Body.String() != "{}\n" {
+			t.Fatalf("Wrong body for %T: %s", v, response.Body.String())
+		}
+	}
+}
+
+func TestJSONEncodingFailure(t *testing.T) {
+	var errs []error
+	m := New(HandlerFunc(func(c *Context) {
+		c.JSON(http.StatusOK, failingMarshaler{})
+		errs = c.Errors()
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Expected status 500, got: ", response.Code)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], errMarshal) {
+		t.Fatal("Encoding error not recorded: ", errs)
+	}
+}
+
+func TestJSONEncodingFailureAfterWrite(t *testing.T) {
+	var errs []error
+	var err error
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "application/json")
+		c.Response.WriteHeader(http.StatusAccepted)
+		c.Response.Write([]byte("["))
+		err = c.JSON(http.StatusOK, failingMarshaler{})
+		errs = c.Errors()
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if !errors.Is(err, errMarshal) {
+		t.Fatal("Encoding error not returned: ", err)
+	}
+	if response.Code != http.StatusAccepted {
+		t.Fatal("Status rewritten after response was written: ", response.Code)
+	}
+	if response.Body.String() != "[" {
+		t.Fatal("Error written to already committed response: ", response.Body.String())
+	}
+	if len(errs) != 1 {
+		t.Fatal("Encoding error not recorded.")
+	}
+}
+
+func TestJSONWriteFailure(t *testing.T) {
+	var errs []error
+	m := New(HandlerFunc(func(c *Context) {
+		c.JSON(http.StatusOK, "value")
+		errs = c.Errors()
+	}))
+	m.ServeHTTP(failingWriter{httptest.NewRecorder()}, nil)
+	if len(errs) != 1 {
+		t.Fatal("Write error not recorded.")
+	}
+}
+
+func TestJSONP(t *testing.T) {
+	var err error
+	m := New(HandlerFunc(func(c *Context) {
+		err = c.JSONP(http.StatusOK, "app.handle_data", map[string]int{"count": 1})
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if err != nil {
+		t.Fatal("Rendering JSONP failed: ", err)
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "application/javascript; charset=utf-8" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if body := strings.TrimSpace(response.Body.String()); body != `/**/app.handle_data({"count":1});` {
+		t.Fatal("Wrong body: ", body)
+	}
+}
+
+func TestJSONPInvalidCallback(t *testing.T) {
+	callbacks := []string{
+		"",
+		"alert(1);cb",
+		"cb</script><script>alert(1)</script>",
+		"1cb",
+		"cb.",
+		strings.Repeat("a", 200),
+	}
+	for _, callback := range callbacks {
+		var err error
+		m := New(HandlerFunc(func(c *Context) {
+			err = c.JSONP(http.StatusOK, callback, "data")
+		}))
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, nil)
+		if err != ErrInvalidCallback {
+			t.Fatalf("Callback %q accepted.", callback)
+		}
+		if response.Body.Len() != 0 {
+			t.Fatalf("Body written for invalid callback %q.", callback)
+		}
+	}
+}
+
+type apiUser struct {
+	Name string `json:"name"`
+}
+
+func TestHandleJSON(t *testing.T) {
+	handler := New().H(HandleJSON(func(c *Context) (apiUser, error) {
+		return apiUser{Name: "mezvaro"}, nil
+	}))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, nil)
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected 200, got: ", response.Code)
+	}
+	if response.Body.String() != "{\"name\":\"mezvaro\"}\n" {
+		t.Fatal("Wrong body: ", response.Body.String())
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "application/json; charset=utf-8" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+}
+
+func TestHandleJSONError(t *testing.T) {
+	handler := New().H(HandleJSON(func(c *Context) (*apiUser, error) {
+		return nil, fs.ErrNotExist
+	}))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, nil)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Error not passed to SendError, status: ", response.Code)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(`<p>{{.}}</p>`))
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	if err := c.HTML(http.StatusCreated, tmpl, "<mezvaro>"); err != nil {
+		t.Fatal("Rendering failed: ", err)
+	}
+	if response.Code != http.StatusCreated {
+		t.Fatal("Wrong status: ", response.Code)
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if response.Body.String() != "<p>&lt;mezvaro&gt;</p>" {
+		t.Fatal("Wrong body: ", response.Body.String())
+	}
+}
+
+func TestHTMLTemplateError(t *testing.T) {
+	tmpl := template.Must(template.New("page").Parse(`{{.Missing}}`))
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	if err := c.HTML(http.StatusOK, tmpl, 42); err == nil {
+		t.Fatal("Expected template error.")
+	}
+	if response.Code != http.StatusInternalServerError || len(c.Errors()) != 1 {
+		t.Fatal("Template error not handled: ", response.Code, c.Errors())
+	}
+}
+
+func TestHTMLCancelled(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	cancel := c.WithCancel()
+	tmpl := template.Must(template.New("page").Funcs(template.FuncMap{
+		"cancelAt": func(i int) string {
+			if i == 1 {
+				cancel()
+			}
+			return ""
+		},
+	}).Parse(`{{range $i, $v := .}}<p>{{$v}}</p>{{cancelAt $i}}{{end}}`))
+	err := c.HTML(http.StatusOK, tmpl, []string{"a", "b", "c", "d"})
+	if err != context.Canceled {
+		t.Fatal("Expected cancellation error, got: ", err)
+	}
+	if response.Body.String() != "<p>a</p><p>b</p>" {
+		t.Fatal("Rendering not stopped after cancellation: ", response.Body.String())
+	}
+	if errs := c.Errors(); len(errs) != 1 || errs[0] != context.Canceled {
+		t.Fatal("Cancellation not recorded: ", errs)
+	}
+}
@@ -0,0 +1,98 @@
+package mezvaro
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReplayConfig configures RejectReplays middleware. Zero values of fields are
+// replaced with defaults.
+type ReplayConfig struct {
+	// HeaderName is name of request header with request ID. Default is
+	// "X-Request-ID".
+	HeaderName string
+	// Window is duration for which seen request IDs are remembered.
+	// Default is 5 minutes.
+	Window time.Duration
+	// Capacity is maximal number of remembered request IDs. When it is
+	// reached, the oldest IDs are forgotten even if window has not passed
+	// yet. Default is 10000.
+	Capacity int
+}
+
+// RejectReplays creates middleware that protects from replayed requests, for
+// example for signed webhook requests. Requests with request ID that has
+// already been seen within window are rejected with 409 Conflict status, and
+// requests without request ID are rejected with 400 Bad Request status. In
+// both cases chain is aborted. IDs are kept in memory, bounded by capacity,
+// so protection is per process.
+func RejectReplays(config ReplayConfig) Handler {
+	if config.HeaderName == "" {
+		config.HeaderName = "X-Request-ID"
+	}
+	if config.Window <= 0 {
+		config.Window = 5 * time.Minute
+	}
+	if config.Capacity <= 0 {
+		config.Capacity = 10000
+	}
+	seen := newSeenIDs(config.Window, config.Capacity)
+	return HandlerFunc(func(c *Context) {
+		id := c.Request.Header.Get(config.HeaderName)
+		if id == "" {
+			http.Error(c.Response, "missing request ID", http.StatusBadRequest)
+			c.Abort()
+			return
+		}
+		if !seen.add(id, time.Now()) {
+			http.Error(c.Response, "duplicate request ID", http.StatusConflict)
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
+
+// seenIDs is bounded set of IDs seen within time window, ordered by time
+// they were seen.
+type seenIDs struct {
+	mu       sync.Mutex
+	window   time.Duration
+	capacity int
+	order    list.List
+	ids      map[string]*list.Element
+}
+
+// seenID is ID together with time it was seen.
+type seenID struct {
+	id   string
+	seen time.Time
+}
+
+func newSeenIDs(window time.Duration, capacity int) *seenIDs {
+	return &seenIDs{window: window, capacity: capacity, ids: make(map[string]*list.Element)}
+}
+
+// add records ID seen at provided time. Returns false if ID has already been
+// seen within window.
+func (s *seenIDs) add(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// forget IDs that are out of window, they are ordered from the oldest
+	for e := s.order.Front(); e != nil && now.Sub(e.Value.(seenID).seen) >= s.window; e = s.order.Front() {
+		delete(s.ids, e.Value.(seenID).id)
+		s.order.Remove(e)
+	}
+	if _, ok := s.ids[id]; ok {
+		return false
+	}
+	if s.order.Len() >= s.capacity {
+		oldest := s.order.Front()
+		delete(s.ids, oldest.Value.(seenID).id)
+		s.order.Remove(oldest)
+	}
+	s.ids[id] = s.order.PushBack(seenID{id: id, seen: now})
+	return true
+}
@@ -0,0 +1,56 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRejectReplays(t *testing.T) {
+	m := New(RejectReplays(ReplayConfig{}))
+	m.UseFunc(func(c *Context) {
+		c.Response.WriteHeader(http.StatusAccepted)
+	})
+	serve := func(id string) int {
+		request, _ := http.NewRequest("POST", "/webhook", nil)
+		if id != "" {
+			request.Header.Set("X-Request-ID", id)
+		}
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		return response.Code
+	}
+	if status := serve("42"); status != http.StatusAccepted {
+		t.Fatal("First request rejected, status: ", status)
+	}
+	if status := serve("42"); status != http.StatusConflict {
+		t.Fatal("Replayed request not rejected, status: ", status)
+	}
+	if status := serve("43"); status != http.StatusAccepted {
+		t.Fatal("Request with new ID rejected, status: ", status)
+	}
+	if status := serve(""); status != http.StatusBadRequest {
+		t.Fatal("Request without ID not rejected, status: ", status)
+	}
+}
+
+func TestSeenIDsWindowAndCapacity(t *testing.T) {
+	seen := newSeenIDs(time.Minute, 2)
+	now := time.Now()
+	seen.add("a", now)
+	if seen.add("a", now.Add(30*time.Second)) {
+		t.Fatal("ID accepted again within window.")
+	}
+	if !seen.add("a", now.Add(2*time.Minute)) {
+		t.Fatal("ID rejected after window passed.")
+	}
+	seen.add("b", now.Add(2*time.Minute))
+	seen.add("c", now.Add(2*time.Minute))
+	if len(seen.ids) != 2 {
+		t.Fatal("Capacity not respected: ", len(seen.ids))
+	}
+	if !seen.add("a", now.Add(2*time.Minute)) {
+		t.Fatal("Oldest ID not evicted when capacity was reached.")
+	}
+}
@@ -0,0 +1,172 @@
+package mezvaro
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// responseWriter wraps http.ResponseWriter provided to Mezvaro and captures
+// information about response written through it.
+type responseWriter struct {
+	http.ResponseWriter
+	status             int
+	written            bool
+	defaultContentType string
+	noSniff            bool
+}
+
+// WriteHeader implements http.ResponseWriter interface. Informational
+// statuses, like 100 Continue, do not commit response, so final status can
+// still be written after them.
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.written && !informational(status) {
+		w.beforeWrite(status)
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter interface.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.written {
+		// do not call WriteHeader explicitly, so underlying writer can
+		// still detect content type if it is not set
+		w.beforeWrite(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// beforeWrite records status and sets default headers before response is
+// committed.
+func (w *responseWriter) beforeWrite(status int) {
+	w.written = true
+	w.status = status
+	if !bodyAllowed(status) {
+		return
+	}
+	header := w.Header()
+	if _, ok := header["Content-Type"]; ok {
+		return
+	}
+	if w.defaultContentType != "" {
+		header.Set("Content-Type", w.defaultContentType)
+	} else if w.noSniff {
+		// prevent standard library from detecting content type
+		header.Set("Content-Type", "application/octet-stream")
+	}
+}
+
+// Flush implements http.Flusher interface, if underlying writer supports it.
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.written {
+			w.beforeWrite(http.StatusOK)
+		}
+		flusher.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker interface, if underlying writer supports it.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// hijack takes over connection of response writer. Unlike type assertion to
+// http.Hijacker, it follows Unwrap methods of wrapped writers, so connection
+// can be hijacked through wrappers that do not implement http.Hijacker.
+// Returned error wraps http.ErrNotSupported if none of writers supports it.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w).Hijack()
+}
+
+// Unwrap returns underlying response writer. It is used by
+// http.ResponseController to access features of original writer.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// informational reports whether status is informational (1xx) status that
+// is followed by final response. 101 Switching Protocols is final response.
+func informational(status int) bool {
+	return status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+}
+
+// bodyAllowed reports whether response with provided status can have body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// Written reports whether response status and headers have already been
+// written, after which they can not be changed anymore.
+func (c *Context) Written() bool {
+	rw, ok := c.Response.(*responseWriter)
+	return ok && rw.written
+}
+
+// responseStatus returns status of written response. If response has not been
+// written yet, 200 OK is returned, since that is status standard library
+// writes for handlers that do not write anything.
+func (c *Context) responseStatus() int {
+	if rw, ok := c.Response.(*responseWriter); ok && rw.written {
+		return rw.status
+	}
+	return http.StatusOK
+}
+
+// SetWriteDeadline sets deadline for writing response to client, so writes
+// to slow or stalled clients fail instead of blocking forever. Zero value
+// means no deadline. If underlying response writer does not support
+// deadlines, error that wraps http.ErrNotSupported is returned.
+func (c *Context) SetWriteDeadline(deadline time.Time) error {
+	return http.NewResponseController(c.Response).SetWriteDeadline(deadline)
+}
+
+// SetReadDeadline sets deadline for reading request body. Zero value means no
+// deadline. If underlying response writer does not support deadlines, error
+// that wraps http.ErrNotSupported is returned.
+func (c *Context) SetReadDeadline(deadline time.Time) error {
+	return http.NewResponseController(c.Response).SetReadDeadline(deadline)
+}
+
+// SetNoCache sets headers that prevent caching of response by clients and
+// intermediaries, including legacy HTTP/1.0 caches. It should be used for
+// responses with sensitive data. Headers have to be set before response is
+// written.
+func (c *Context) SetNoCache() {
+	header := c.Response.Header()
+	header.Set("Cache-Control", "no-store, no-cache, must-revalidate")
+	header.Set("Pragma", "no-cache")
+	header.Set("Expires", "0")
+}
+
+// EmitServerTiming adds metric with provided name and duration to
+// Server-Timing header of response, so it is shown in developer tools of
+// browser. Each call adds new metric, so multiple middlewares can report
+// their own durations. Name has to be valid header token, like "db" or
+// "auth". Metrics have to be emitted before response is written.
+func (c *Context) EmitServerTiming(name string, d time.Duration) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	c.Response.Header().Add("Server-Timing", name+";dur="+ms)
+}
+
+// SniffContentType enables or disables detection of content type of response
+// from its body, which standard library does for responses without
+// Content-Type header, and which browsers do as well. Detection can guess
+// wrong, which can lead to content being interpreted as something it is not,
+// like HTML. When disabled, X-Content-Type-Options header is set to
+// "nosniff", and responses written without Content-Type header (or default
+// one, see Mezvaro.DefaultContentType) get "application/octet-stream".
+// Detection is enabled by default. It has to be configured before response
+// is written.
+func (c *Context) SniffContentType(enable bool) {
+	if enable {
+		c.Response.Header().Del("X-Content-Type-Options")
+	} else {
+		c.Response.Header().Set("X-Content-Type-Options", "nosniff")
+	}
+	if rw, ok := c.Response.(*responseWriter); ok {
+		rw.noSniff = !enable
+	}
+}
@@ -0,0 +1,187 @@
+package mezvaro
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDefaultContentType(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Write([]byte("<html></html>"))
+	}))
+	m.DefaultContentType = "text/plain; charset=utf-8"
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatal("Default content type not set, got: ", ct)
+	}
+}
+
+func TestDefaultContentTypeNotConfigured(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Write([]byte("<html></html>"))
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Fatal("Content type not detected, got: ", ct)
+	}
+}
+
+func TestDefaultContentTypeExplicit(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "application/json")
+		c.Response.WriteHeader(http.StatusCreated)
+		c.Response.Write([]byte("{}"))
+	}))
+	m.DefaultContentType = "text/plain; charset=utf-8"
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatal("Explicit content type overridden, got: ", ct)
+	}
+	if response.Code != http.StatusCreated {
+		t.Fatal("Expected status 201, got: ", response.Code)
+	}
+}
+
+func TestDefaultContentTypeInherited(t *testing.T) {
+	m := New()
+	m.DefaultContentType = "text/plain; charset=utf-8"
+	response := httptest.NewRecorder()
+	m.Fork().HF(func(c *Context) {
+		c.Response.Write([]byte("<html></html>"))
+	}).ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatal("Default content type of parent not used, got: ", ct)
+	}
+}
+
+func TestDefaultContentTypeNoContent(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.WriteHeader(http.StatusNoContent)
+	}))
+	m.DefaultContentType = "text/plain; charset=utf-8"
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "" {
+		t.Fatal("Content type set for response without body: ", ct)
+	}
+}
+
+// deadlineRecorder is response writer that supports connection deadlines.
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+func (r *deadlineRecorder) SetReadDeadline(deadline time.Time) error {
+	r.readDeadline = deadline
+	return nil
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.writeDeadline = deadline
+	return nil
+}
+
+func TestSetDeadlines(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+	var readErr, writeErr error
+	m := New(HandlerFunc(func(c *Context) {
+		readErr = c.SetReadDeadline(deadline)
+		writeErr = c.SetWriteDeadline(deadline)
+	}))
+	response := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	m.ServeHTTP(response, nil)
+	if readErr != nil || writeErr != nil {
+		t.Fatal("Setting deadlines failed: ", readErr, writeErr)
+	}
+	if !response.readDeadline.Equal(deadline) {
+		t.Fatal("Read deadline not set.")
+	}
+	if !response.writeDeadline.Equal(deadline) {
+		t.Fatal("Write deadline not set.")
+	}
+}
+
+func TestSetDeadlinesNotSupported(t *testing.T) {
+	var readErr, writeErr error
+	m := New(HandlerFunc(func(c *Context) {
+		readErr = c.SetReadDeadline(time.Now())
+		writeErr = c.SetWriteDeadline(time.Now())
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if !errors.Is(readErr, http.ErrNotSupported) {
+		t.Fatal("Expected ErrNotSupported for read deadline, got: ", readErr)
+	}
+	if !errors.Is(writeErr, http.ErrNotSupported) {
+		t.Fatal("Expected ErrNotSupported for write deadline, got: ", writeErr)
+	}
+}
+
+func TestSetNoCache(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	c.SetNoCache()
+	c.Response.Write([]byte("secret"))
+	expected := map[string]string{
+		"Cache-Control": "no-store, no-cache, must-revalidate",
+		"Pragma":        "no-cache",
+		"Expires":       "0",
+	}
+	for name, value := range expected {
+		if actual := response.Header().Get(name); actual != value {
+			t.Fatal("Wrong ", name, " header: ", actual)
+		}
+	}
+}
+
+func TestEmitServerTiming(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := newContext(response, nil, nil, nil)
+	c.EmitServerTiming("db", 53*time.Millisecond)
+	c.EmitServerTiming("auth", 1500*time.Microsecond)
+	timings := response.Header().Values("Server-Timing")
+	if len(timings) != 2 || timings[0] != "db;dur=53" || timings[1] != "auth;dur=1.5" {
+		t.Fatal("Wrong Server-Timing header: ", timings)
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	for _, enable := range []bool{true, false} {
+		m := New(HandlerFunc(func(c *Context) {
+			c.SniffContentType(false)
+			c.SniffContentType(enable)
+			c.Response.Write([]byte("<html></html>"))
+		}))
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, nil)
+		ct := response.Header().Get("Content-Type")
+		nosniff := response.Header().Get("X-Content-Type-Options")
+		if enable && (ct != "text/html; charset=utf-8" || nosniff != "") {
+			t.Fatal("Content type not detected: ", ct, nosniff)
+		}
+		if !enable && (ct != "application/octet-stream" || nosniff != "nosniff") {
+			t.Fatal("Content type detected: ", ct, nosniff)
+		}
+	}
+}
+
+func TestSniffContentTypeExplicit(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.SniffContentType(false)
+		c.Response.Header().Set("Content-Type", "text/plain")
+		c.Response.Write([]byte("<html></html>"))
+	}))
+	m.DefaultContentType = "application/json"
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if ct := response.Header().Get("Content-Type"); ct != "text/plain" {
+		t.Fatal("Explicit content type replaced: ", ct)
+	}
+}
@@ -0,0 +1,294 @@
+package mezvaro
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaError describes single violation of JSON schema found by
+// ValidateSchema.
+type SchemaError struct {
+	// Field is path of invalid value in request body, with names of object
+	// properties and indexes of array items separated by dots, for example
+	// "items.0.name". Empty for root value.
+	Field string `json:"field"`
+	// Message describes violated constraint.
+	Message string `json:"message"`
+}
+
+// unsupportedSchemaKeywords are keywords that change meaning of schema, so
+// ignoring them would accept invalid bodies.
+var unsupportedSchemaKeywords = []string{
+	"$ref", "allOf", "anyOf", "oneOf", "not", "if", "then", "else",
+	"patternProperties", "dependentSchemas", "dependencies",
+}
+
+// jsonSchema is compiled JSON schema.
+type jsonSchema struct {
+	Types                []string
+	Properties           map[string]*jsonSchema
+	Required             []string
+	AdditionalProperties *bool
+	Items                *jsonSchema
+	Enum                 []interface{}
+	Minimum              *float64
+	Maximum              *float64
+	ExclusiveMinimum     *float64
+	ExclusiveMaximum     *float64
+	MinLength            *int
+	MaxLength            *int
+	Pattern              *regexp.Regexp
+	MinItems             *int
+	MaxItems             *int
+}
+
+// ValidateSchema returns middleware that validates JSON body of request
+// against provided JSON schema before rest of the chain is executed. Requests
+// with invalid body are rejected with 400 Bad Request status and JSON body
+// that lists violations as SchemaError values under "errors" key, and chain
+// is aborted. Body is cached (see Body), so handlers can still read it.
+//
+// Schema is compiled once, when middleware is created, and it panics if
+// schema is not valid. Supported keywords are type, properties, required,
+// additionalProperties (boolean only), items, enum, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum (numbers only), minLength, maxLength,
+// pattern, minItems and maxItems. Annotations, like title or format, are
+// ignored. Schemas with composition keywords and references, like allOf or
+// $ref, are not supported and cause panic.
+func ValidateSchema(schema []byte) Handler {
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		panic("mezvaro: invalid JSON schema: " + err.Error())
+	}
+	return HandlerFunc(func(c *Context) {
+		body, err := c.Body()
+		if err != nil {
+			c.SendError(err)
+			return
+		}
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		if err := decoder.Decode(&value); err != nil {
+			c.AbortWithJSON(http.StatusBadRequest, map[string]interface{}{
+				"errors": []SchemaError{{Message: newJSONBindError(err).Message}},
+			})
+			return
+		}
+		if errs := compiled.validate("", value, nil); len(errs) > 0 {
+			c.AbortWithJSON(http.StatusBadRequest, map[string]interface{}{"errors": errs})
+			return
+		}
+		c.Next()
+	})
+}
+
+// compileSchema parses JSON schema.
+func compileSchema(data []byte) (*jsonSchema, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	for _, keyword := range unsupportedSchemaKeywords {
+		if _, ok := raw[keyword]; ok {
+			return nil, fmt.Errorf("keyword %q is not supported", keyword)
+		}
+	}
+	var def struct {
+		Type                 json.RawMessage            `json:"type"`
+		Properties           map[string]json.RawMessage `json:"properties"`
+		Required             []string                   `json:"required"`
+		AdditionalProperties *bool                      `json:"additionalProperties"`
+		Items                json.RawMessage            `json:"items"`
+		Enum                 []interface{}              `json:"enum"`
+		Minimum              *float64                   `json:"minimum"`
+		Maximum              *float64                   `json:"maximum"`
+		ExclusiveMinimum     *float64                   `json:"exclusiveMinimum"`
+		ExclusiveMaximum     *float64                   `json:"exclusiveMaximum"`
+		MinLength            *int                       `json:"minLength"`
+		MaxLength            *int                       `json:"maxLength"`
+		Pattern              string                     `json:"pattern"`
+		MinItems             *int                       `json:"minItems"`
+		MaxItems             *int                       `json:"maxItems"`
+	}
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	s := &jsonSchema{
+		Required:             def.Required,
+		AdditionalProperties: def.AdditionalProperties,
+		Enum:                 def.Enum,
+		Minimum:              def.Minimum,
+		Maximum:              def.Maximum,
+		ExclusiveMinimum:     def.ExclusiveMinimum,
+		ExclusiveMaximum:     def.ExclusiveMaximum,
+		MinLength:            def.MinLength,
+		MaxLength:            def.MaxLength,
+		MinItems:             def.MinItems,
+		MaxItems:             def.MaxItems,
+	}
+	if len(def.Type) > 0 {
+		var single string
+		if err := json.Unmarshal(def.Type, &single); err == nil {
+			s.Types = []string{single}
+		} else if err := json.Unmarshal(def.Type, &s.Types); err != nil {
+			return nil, fmt.Errorf("invalid type: %s", def.Type)
+		}
+	}
+	if def.Pattern != "" {
+		pattern, err := regexp.Compile(def.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		s.Pattern = pattern
+	}
+	if len(def.Items) > 0 {
+		items, err := compileSchema(def.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+		s.Items = items
+	}
+	if len(def.Properties) > 0 {
+		s.Properties = make(map[string]*jsonSchema, len(def.Properties))
+		for name, raw := range def.Properties {
+			property, err := compileSchema(raw)
+			if err != nil {
+				return nil, fmt.Errorf("properties.%s: %v", name, err)
+			}
+			s.Properties[name] = property
+		}
+	}
+	return s, nil
+}
+
+// validate checks value decoded from JSON against schema and appends found
+// violations to errs.
+func (s *jsonSchema) validate(field string, value interface{}, errs []SchemaError) []SchemaError {
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, SchemaError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if len(s.Types) > 0 && !s.matchesType(value) {
+		fail("must be of type %s", strings.Join(s.Types, " or "))
+		return errs
+	}
+	if len(s.Enum) > 0 && !s.inEnum(value) {
+		fail("must be one of allowed values")
+	}
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			fail("must be greater than or equal to %v", *s.Minimum)
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			fail("must be less than or equal to %v", *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+			fail("must be greater than %v", *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+			fail("must be less than %v", *s.ExclusiveMaximum)
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != nil && !s.Pattern.MatchString(v) {
+			fail("must match pattern %s", s.Pattern)
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				errs = s.Items.validate(joinField(field, fmt.Sprint(i)), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, SchemaError{Field: joinField(field, name), Message: "is required"})
+			}
+		}
+		// iterate in sorted order, so errors are reported deterministically
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, ok := s.Properties[name]; ok {
+				errs = property.validate(joinField(field, name), v[name], errs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				errs = append(errs, SchemaError{Field: joinField(field, name), Message: "is not allowed"})
+			}
+		}
+	}
+	return errs
+}
+
+// matchesType checks if value is of one of types allowed by schema.
+func (s *jsonSchema) matchesType(value interface{}) bool {
+	for _, t := range s.Types {
+		switch v := value.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && v == math.Trunc(v) {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inEnum checks if value is one of values allowed by schema.
+func (s *jsonSchema) inEnum(value interface{}) bool {
+	for _, allowed := range s.Enum {
+		if reflect.DeepEqual(allowed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// joinField appends name to path of field.
+func joinField(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
@@ -0,0 +1,92 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var userSchema = []byte(`{
+	"type": "object",
+	"required": ["name", "age"],
+	"additionalProperties": false,
+	"properties": {
+		"name": {"type": "string", "minLength": 2},
+		"age": {"type": "integer", "minimum": 0},
+		"role": {"enum": ["admin", "user"]},
+		"tags": {"type": "array", "items": {"type": "string", "pattern": "^[a-z]+$"}}
+	}
+}`)
+
+func validateSchema(body string) (*httptest.ResponseRecorder, map[string]string) {
+	var bound map[string]string
+	m := New(ValidateSchema(userSchema))
+	m.UseFunc(func(c *Context) {
+		var user struct {
+			Name string `json:"name"`
+		}
+		c.BindJSON(&user)
+		bound = map[string]string{"name": user.Name}
+	})
+	request, _ := http.NewRequest("POST", "/users", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response, bound
+}
+
+func TestValidateSchemaValid(t *testing.T) {
+	response, bound := validateSchema(`{"name": "mezvaro", "age": 3, "role": "admin", "tags": ["go"]}`)
+	if response.Code != http.StatusOK {
+		t.Fatal("Valid body rejected: ", response.Body.String())
+	}
+	if bound["name"] != "mezvaro" {
+		t.Fatal("Body not readable by handler after validation.")
+	}
+}
+
+func TestValidateSchemaInvalid(t *testing.T) {
+	response, bound := validateSchema(`{"name": "m", "age": 1.5, "role": "root", "tags": ["Go"], "extra": true}`)
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Expected 400 for invalid body, got: ", response.Code)
+	}
+	if bound != nil {
+		t.Fatal("Handler executed for invalid body.")
+	}
+	var result struct {
+		Errors []SchemaError `json:"errors"`
+	}
+	if err := json.Unmarshal(response.Body.Bytes(), &result); err != nil {
+		t.Fatal("Response is not JSON: ", response.Body.String())
+	}
+	fields := map[string]bool{}
+	for _, e := range result.Errors {
+		fields[e.Field] = true
+	}
+	for _, field := range []string{"name", "age", "role", "tags.0", "extra"} {
+		if !fields[field] {
+			t.Fatal("Violation of field ", field, " not reported: ", result.Errors)
+		}
+	}
+}
+
+func TestValidateSchemaMissingRequired(t *testing.T) {
+	response, _ := validateSchema(`{"name": "mezvaro"}`)
+	if response.Code != http.StatusBadRequest || !strings.Contains(response.Body.String(), `"field":"age"`) {
+		t.Fatal("Missing required field not reported: ", response.Body.String())
+	}
+	response, _ = validateSchema(`{"name": `)
+	if response.Code != http.StatusBadRequest {
+		t.Fatal("Malformed JSON not rejected: ", response.Code)
+	}
+}
+
+func TestValidateSchemaUnsupported(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Schema with unsupported keyword accepted.")
+		}
+	}()
+	ValidateSchema([]byte(`{"anyOf": [{"type": "string"}, {"type": "number"}]}`))
+}
@@ -0,0 +1,110 @@
+package mezvaro
+
+import (
+	"container/list"
+	"sync"
+
+	"golang.org/x/net/context"
+)
+
+// Semaphore limits access to shared resource to total weight of holders. It
+// is minimal equivalent of semaphore.Weighted from golang.org/x/sync. Waiters
+// are served in order they started waiting, so large requests are not
+// starved by small ones.
+type Semaphore struct {
+	mu      sync.Mutex
+	size    int64
+	cur     int64
+	waiters list.List
+}
+
+// semaphoreWaiter is acquirer waiting for semaphore.
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// NewSemaphore creates semaphore with provided maximal total weight.
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Acquire acquires semaphore with weight n, blocking until resources are
+// available or context is done. On success nil is returned, otherwise
+// context error is returned and semaphore is left unchanged.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+	if n > s.size {
+		// can never succeed, so only wait for context
+		s.mu.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-ready:
+			// acquired right after context was done, give it back
+			s.cur -= n
+			s.notifyWaiters()
+		default:
+			isFront := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			if isFront && s.size > s.cur {
+				s.notifyWaiters()
+			}
+		}
+		return ctx.Err()
+	}
+}
+
+// Release releases semaphore with weight n.
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	if s.cur < 0 {
+		panic("mezvaro: semaphore released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// notifyWaiters wakes up waiters, in order, for which there are enough
+// resources. Caller has to hold semaphore lock.
+func (s *Semaphore) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(semaphoreWaiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
+
+// AcquireLock acquires semaphore with weight n, waiting until resources are
+// available or request context is done, in which case context error is
+// returned. This prevents handlers from staying blocked on shared resources
+// after request is cancelled or server shuts down. Semaphore has to be
+// released with Release when access to resource is not needed anymore.
+func (c *Context) AcquireLock(l *Semaphore, n int64) error {
+	return l.Acquire(c.Ctx(), n)
+}
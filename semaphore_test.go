@@ -0,0 +1,49 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAcquireLock(t *testing.T) {
+	l := NewSemaphore(2)
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if err := c.AcquireLock(l, 2); err != nil {
+		t.Fatal("Acquire failed: ", err)
+	}
+	acquired := make(chan error)
+	go func() {
+		acquired <- c.AcquireLock(l, 1)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("Semaphore acquired while held.")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.Release(2)
+	if err := <-acquired; err != nil {
+		t.Fatal("Waiting acquire failed after release: ", err)
+	}
+	l.Release(1)
+}
+
+func TestAcquireLockCancelled(t *testing.T) {
+	l := NewSemaphore(1)
+	c := newContext(httptest.NewRecorder(), nil, nil, nil)
+	if err := c.AcquireLock(l, 1); err != nil {
+		t.Fatal("Acquire failed: ", err)
+	}
+	waiting := newContext(httptest.NewRecorder(), nil, nil, nil)
+	cancel := waiting.WithTimeout(20 * time.Millisecond)
+	defer cancel()
+	if err := waiting.AcquireLock(l, 1); err == nil {
+		t.Fatal("Acquire did not fail when context expired.")
+	}
+
+	// semaphore is usable after cancelled waiter gave up
+	l.Release(1)
+	if err := c.AcquireLock(l, 1); err != nil {
+		t.Fatal("Semaphore left in inconsistent state: ", err)
+	}
+}
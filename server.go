@@ -0,0 +1,98 @@
+package mezvaro
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// ShutdownTimeout is maximal time Run and RunTLS wait for active requests to
+// finish during graceful shutdown.
+var ShutdownTimeout = 10 * time.Second
+
+// Timeouts of servers started by Run and RunTLS, which protect from clients
+// that keep connections open without sending requests, like in slowloris
+// attack. Reading of request bodies and writing of responses are not limited,
+// so uploads and streams are not cut off, but they can be limited per request
+// with SetReadDeadline and SetWriteDeadline methods of Context.
+var (
+	// ReadHeaderTimeout is maximal time for reading request headers.
+	ReadHeaderTimeout = 10 * time.Second
+	// IdleTimeout is maximal time to wait for next request on keep-alive
+	// connection.
+	IdleTimeout = 2 * time.Minute
+)
+
+// Run starts HTTP server on provided address that serves requests with
+// Mezvaro, like http.ListenAndServe does. Server is shut down gracefully when
+// process receives SIGINT or SIGTERM signal: new connections are refused and
+// active requests are given ShutdownTimeout to finish. Server uses
+// ReadHeaderTimeout and IdleTimeout. Returns nil after graceful shutdown,
+// otherwise error that stopped server.
+func (m *Mezvaro) Run(addr string) error {
+	return m.run(addr, "", "")
+}
+
+// RunTLS works like Run, but server serves HTTPS requests, using provided
+// certificate and key files, like http.ListenAndServeTLS does.
+func (m *Mezvaro) RunTLS(addr, certFile, keyFile string) error {
+	return m.run(addr, certFile, keyFile)
+}
+
+// run listens on provided address and serves requests until process is
+// interrupted.
+func (m *Mezvaro) run(addr, certFile, keyFile string) error {
+	if addr == "" {
+		addr = ":http"
+		if certFile != "" {
+			addr = ":https"
+		}
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return m.serveUntil(ctx, ln, certFile, keyFile)
+}
+
+// serveUntil serves requests accepted by listener until context is done, and
+// then shuts server down gracefully. If certificate file is provided, TLS is
+// used.
+func (m *Mezvaro) serveUntil(ctx context.Context, ln net.Listener, certFile, keyFile string) error {
+	srv := &http.Server{
+		Handler:           m,
+		ReadHeaderTimeout: ReadHeaderTimeout,
+		IdleTimeout:       IdleTimeout,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		if certFile != "" {
+			errs <- srv.ServeTLS(ln, certFile, keyFile)
+		} else {
+			errs <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
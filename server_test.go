@@ -0,0 +1,82 @@
+package mezvaro
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestServeUntil(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Write([]byte("running"))
+	}))
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+	go func() {
+		errs <- m.serveUntil(ctx, ln, "", "")
+	}()
+
+	response, err := http.Get("http://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal("Request failed: ", err)
+	}
+	body, _ := io.ReadAll(response.Body)
+	response.Body.Close()
+	if string(body) != "running" {
+		t.Fatal("Wrong response: ", string(body))
+	}
+
+	cancel()
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatal("Server not shut down gracefully: ", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server not shut down.")
+	}
+	if _, err := http.Get("http://" + ln.Addr().String()); err == nil {
+		t.Fatal("Server still accepts requests after shutdown.")
+	}
+}
+
+func TestRunInvalidAddress(t *testing.T) {
+	if err := New().Run("invalid:address:1"); err == nil {
+		t.Fatal("Expected error for invalid address.")
+	}
+}
+
+func TestServeUntilReadHeaderTimeout(t *testing.T) {
+	previous := ReadHeaderTimeout
+	ReadHeaderTimeout = 50 * time.Millisecond
+	defer func() { ReadHeaderTimeout = previous }()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go New().serveUntil(ctx, ln, "", "")
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal("Connection failed: ", err)
+	}
+	defer conn.Close()
+	// headers are never finished, like in slowloris attack
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n"))
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	io.ReadAll(conn)
+	if time.Since(start) >= 5*time.Second {
+		t.Fatal("Connection with unfinished headers not closed.")
+	}
+}
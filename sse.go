@@ -0,0 +1,129 @@
+package mezvaro
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SSEvent is single server-sent event.
+type SSEvent struct {
+	// Event is type of event. If empty, client treats it as "message".
+	Event string
+	// ID of event, reported back by client in Last-Event-ID header when
+	// it reconnects.
+	ID string
+	// Data of event. Strings and byte slices are sent as they are, other
+	// values are encoded as JSON.
+	Data interface{}
+	// Retry is reconnection time client should use. Zero means it is not
+	// sent.
+	Retry time.Duration
+}
+
+// SSEvent writes server-sent event with provided type and data, and flushes
+// it to client. Headers for event stream are set before first event is
+// written, if response has not been written yet.
+func (c *Context) SSEvent(event string, data interface{}) error {
+	return c.writeSSE(SSEvent{Event: event, Data: data})
+}
+
+// SSEStream writes events received from channel as server-sent events, until
+// channel is closed or context is done, in which case context error is
+// returned. If keepAlive is positive, comment line ": ping" is written
+// whenever there were no events for keepAlive duration, so proxies do not
+// drop idle connections. Keep-alive stops when stream ends.
+func (c *Context) SSEStream(events <-chan SSEvent, keepAlive time.Duration) error {
+	c.sseHeaders()
+	var ping <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := c.writeSSE(event); err != nil {
+				return err
+			}
+		case <-ping:
+			if err := c.writeSSEFrame([]byte(": ping\n\n")); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// sseHeaders sets headers of event stream, if response has not been written
+// yet.
+func (c *Context) sseHeaders() {
+	if c.Written() {
+		return
+	}
+	header := c.Response.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	c.Response.WriteHeader(http.StatusOK)
+}
+
+// writeSSE encodes event and writes it to response.
+func (c *Context) writeSSE(event SSEvent) error {
+	var data []byte
+	switch d := event.Data.(type) {
+	case string:
+		data = []byte(d)
+	case []byte:
+		data = d
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return err
+		}
+		data = encoded
+	}
+	var frame bytes.Buffer
+	if event.ID != "" {
+		frame.WriteString("id: " + stripNewlines(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		frame.WriteString("event: " + stripNewlines(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		frame.WriteString("retry: " + strconv.FormatInt(event.Retry.Milliseconds(), 10) + "\n")
+	}
+	// every line of data has to be sent in separate data field
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		frame.WriteString("data: ")
+		frame.Write(line)
+		frame.WriteByte('\n')
+	}
+	frame.WriteByte('\n')
+	c.sseHeaders()
+	return c.writeSSEFrame(frame.Bytes())
+}
+
+// writeSSEFrame writes frame of event stream and flushes it to client.
+func (c *Context) writeSSEFrame(frame []byte) error {
+	if _, err := c.Response.Write(frame); err != nil {
+		return err
+	}
+	if flusher, ok := c.Response.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// stripNewlines removes line breaks, which would break framing of event.
+func stripNewlines(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
@@ -0,0 +1,65 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestSSEvent(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	c.SSEvent("update", map[string]int{"count": 1})
+	c.SSEvent("", "first\nsecond")
+	expected := "event: update\ndata: {\"count\":1}\n\ndata: first\ndata: second\n\n"
+	if response.Body.String() != expected {
+		t.Fatal("Wrong events: ", response.Body.String())
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if !response.Flushed {
+		t.Fatal("Events not flushed.")
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	events := make(chan SSEvent, 2)
+	events <- SSEvent{ID: "1", Event: "created", Data: "user", Retry: time.Second}
+	close(events)
+	if err := c.SSEStream(events, 0); err != nil {
+		t.Fatal("Stream failed: ", err)
+	}
+	expected := "id: 1\nevent: created\nretry: 1000\ndata: user\n\n"
+	if response.Body.String() != expected {
+		t.Fatal("Wrong events: ", response.Body.String())
+	}
+}
+
+func TestSSEStreamKeepAlive(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	cancel := c.WithTimeout(100 * time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.SSEStream(nil, 10*time.Millisecond)
+	if err != context.DeadlineExceeded {
+		t.Fatal("Expected deadline error, got: ", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("Stream not stopped on cancel.")
+	}
+	pings := strings.Count(response.Body.String(), ": ping\n\n")
+	if pings < 3 {
+		t.Fatal("Keep-alive not emitted during idle period, pings: ", pings)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if strings.Count(response.Body.String(), ": ping\n\n") != pings {
+		t.Fatal("Keep-alive emitted after stream stopped.")
+	}
+}
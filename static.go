@@ -0,0 +1,90 @@
+package mezvaro
+
+import (
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ServeFiles creates handler that serves static files from provided file
+// system, based on URL path of request. If client accepts gzip encoding and
+// file system contains pre-compressed sibling of requested file (file with
+// same name and ".gz" extension), compressed file is served instead, so there
+// is no need for compression on the fly. Otherwise, uncompressed file is served.
+//
+// ServeFiles is intended to be used as final handler, it does not call next
+// handler in chain.
+func ServeFiles(root http.FileSystem) Handler {
+	fileServer := http.FileServer(root)
+	return HandlerFunc(func(c *Context) {
+		name := path.Clean("/" + c.Request.URL.Path)
+		if !strings.HasSuffix(c.Request.URL.Path, "/") && servePrecompressed(c, root, name) {
+			return
+		}
+		fileServer.ServeHTTP(c.Response, c.Request)
+	})
+}
+
+// servePrecompressed serves gzip compressed sibling of file with provided name,
+// if it exists and client accepts it. Returns boolean indicating if response
+// has been served.
+func servePrecompressed(c *Context, root http.FileSystem, name string) bool {
+	f, err := root.Open(name + ".gz")
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		return false
+	}
+	// response depends on Accept-Encoding header whenever compressed
+	// variant exists, even if uncompressed file is served
+	c.Response.Header().Add("Vary", "Accept-Encoding")
+	if !c.AcceptsEncoding("gzip") {
+		return false
+	}
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Response.Header().Set("Content-Type", contentType)
+	c.Response.Header().Set("Content-Encoding", "gzip")
+	http.ServeContent(c.Response, c.Request, name, stat.ModTime(), f)
+	return true
+}
+
+// ServeEmbedded creates handler that serves files from directory root of
+// provided file system, usually embed.FS with assets embedded into binary,
+// through chain of used instance of Mezvaro. URL prefix is removed from path
+// of request before file is looked up, so for prefix "/assets/" and root
+// "static", request for "/assets/app.js" is served with file "static/app.js".
+// Files are served like in ServeFiles, including index files, detection of
+// content types, range requests and pre-compressed variants. Requests for
+// missing files and requests outside of prefix get 404 Not Found response.
+// It panics if root is not valid path.
+func (m *Mezvaro) ServeEmbedded(urlPrefix string, fsys fs.FS, root string) http.Handler {
+	if root != "" && root != "." {
+		sub, err := fs.Sub(fsys, root)
+		if err != nil {
+			panic(fmt.Sprintf("mezvaro: invalid root of embedded files: %v", err))
+		}
+		fsys = sub
+	}
+	files := ServeFiles(http.FS(fsys))
+	return m.HF(func(c *Context) {
+		name, ok := strings.CutPrefix(c.Request.URL.Path, urlPrefix)
+		if !ok {
+			http.NotFound(c.Response, c.Request)
+			return
+		}
+		r := c.Request.Clone(c.Request.Context())
+		r.URL.Path = "/" + strings.TrimPrefix(name, "/")
+		r.URL.RawPath = ""
+		c.Request = r
+		files.Handle(c)
+	})
+}
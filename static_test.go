@@ -0,0 +1,163 @@
+package mezvaro
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+const staticContent = "static file content"
+
+func staticDir(t *testing.T) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte(staticContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(staticContent))
+	gz.Close()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt.gz"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "plain.txt"), []byte(staticContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestServeFilesGzip(t *testing.T) {
+	m := New(ServeFiles(http.Dir(staticDir(t))))
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/file.txt", nil)
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Fatal("Expected status 200, got: ", response.Code)
+	}
+	if response.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("Compressed file not served.")
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	gz, err := gzip.NewReader(response.Body)
+	if err != nil {
+		t.Fatal("Response is not gzip encoded: ", err)
+	}
+	body, _ := io.ReadAll(gz)
+	if string(body) != staticContent {
+		t.Fatal("Wrong content: ", string(body))
+	}
+}
+
+func TestServeFilesGzipNotAccepted(t *testing.T) {
+	m := New(ServeFiles(http.Dir(staticDir(t))))
+	for _, acceptEncoding := range []string{"", "deflate", "gzip;q=0"} {
+		response := httptest.NewRecorder()
+		request, _ := http.NewRequest("GET", "/file.txt", nil)
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+		m.ServeHTTP(response, request)
+		if response.Header().Get("Content-Encoding") != "" {
+			t.Fatal("Compressed file served for Accept-Encoding: ", acceptEncoding)
+		}
+		if response.Body.String() != staticContent {
+			t.Fatal("Wrong content: ", response.Body.String())
+		}
+	}
+}
+
+func TestServeFilesWithoutCompressedVariant(t *testing.T) {
+	m := New(ServeFiles(http.Dir(staticDir(t))))
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/plain.txt", nil)
+	request.Header.Set("Accept-Encoding", "gzip")
+	m.ServeHTTP(response, request)
+	if response.Header().Get("Content-Encoding") != "" {
+		t.Fatal("Content encoding set for uncompressed file.")
+	}
+	if response.Body.String() != staticContent {
+		t.Fatal("Wrong content: ", response.Body.String())
+	}
+}
+
+func TestServeFilesNotFound(t *testing.T) {
+	m := New(ServeFiles(http.Dir(staticDir(t))))
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("GET", "/missing.txt", nil)
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected status 404, got: ", response.Code)
+	}
+}
+
+func embeddedHandler() http.Handler {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("console.log('app')"))
+	gz.Close()
+	fsys := fstest.MapFS{
+		"static/app.js":     {Data: []byte("console.log('app')")},
+		"static/app.js.gz":  {Data: compressed.Bytes()},
+		"static/index.html": {Data: []byte("<html>index</html>")},
+		"other.txt":         {Data: []byte("outside of root")},
+	}
+	m := New(HandlerFunc(func(c *Context) {
+		c.Response.Header().Set("X-Chain", "executed")
+	}))
+	return m.ServeEmbedded("/assets/", fsys, "static")
+}
+
+func serveEmbedded(target string, headers map[string]string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest("GET", target, nil)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response := httptest.NewRecorder()
+	embeddedHandler().ServeHTTP(response, request)
+	return response
+}
+
+func TestServeEmbedded(t *testing.T) {
+	response := serveEmbedded("/assets/app.js", nil)
+	if response.Code != http.StatusOK || response.Body.String() != "console.log('app')" {
+		t.Fatal("Wrong response: ", response.Code, response.Body.String())
+	}
+	if ct := response.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/javascript") {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if response.Header().Get("X-Chain") != "executed" {
+		t.Fatal("Files not served through chain.")
+	}
+
+	response = serveEmbedded("/assets/", nil)
+	if response.Code != http.StatusOK || response.Body.String() != "<html>index</html>" {
+		t.Fatal("Index file not served: ", response.Code, response.Body.String())
+	}
+}
+
+func TestServeEmbeddedRangeAndGzip(t *testing.T) {
+	response := serveEmbedded("/assets/app.js", map[string]string{"Range": "bytes=0-6"})
+	if response.Code != http.StatusPartialContent || response.Body.String() != "console" {
+		t.Fatal("Range not served: ", response.Code, response.Body.String())
+	}
+	response = serveEmbedded("/assets/app.js", map[string]string{"Accept-Encoding": "gzip"})
+	if response.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("Pre-compressed variant not served.")
+	}
+}
+
+func TestServeEmbeddedNotFound(t *testing.T) {
+	for _, target := range []string{"/assets/missing.js", "/assets/../other.txt", "/other.txt"} {
+		if response := serveEmbedded(target, nil); response.Code != http.StatusNotFound {
+			t.Fatal("Expected 404 for ", target, ", got: ", response.Code)
+		}
+	}
+}
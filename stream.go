@@ -0,0 +1,325 @@
+package mezvaro
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRangeNotSatisfiable is returned by StreamReader when Range header of
+// request is malformed or does not overlap streamed content. Response with
+// 416 Requested Range Not Satisfiable status has already been written when
+// this error is returned.
+var ErrRangeNotSatisfiable = errors.New("mezvaro: requested range not satisfiable")
+
+// streamBufferSize is size of buffer used for copying streamed data.
+const streamBufferSize = 32 * 1024
+
+// StreamFlushInterval is maximal time between flushes of data streamed with
+// StreamReader. Shorter interval delivers data to clients sooner, at cost of
+// more writes to network.
+var StreamFlushInterval = 100 * time.Millisecond
+
+// StreamReader responds with provided status and content type and copies
+// data from r to response. Data is flushed to client periodically (see
+// StreamFlushInterval), so this is suitable for proxying streams of files or
+// blobs. Copying stops when context is done and context error is returned.
+// Returns number of bytes written to response.
+//
+// If r implements io.ReadSeeker and status is 200 OK, Range header of request
+// is honored: for single byte range only requested part is written with 206
+// Partial Content status. Requests with multiple ranges receive whole content.
+func (c *Context) StreamReader(status int, contentType string, r io.Reader) (int64, error) {
+	if contentType != "" {
+		c.Response.Header().Set("Content-Type", contentType)
+	}
+	if seeker, ok := r.(io.ReadSeeker); ok && status == http.StatusOK {
+		c.Response.Header().Set("Accept-Ranges", "bytes")
+		if c.Request != nil && c.Request.Header.Get("Range") != "" {
+			return c.streamRange(seeker)
+		}
+	}
+	c.Response.WriteHeader(status)
+	return c.stream(r)
+}
+
+// File responds with content of file with provided name. Unlike
+// StreamReader, it handles conditional and range requests and detects content
+// type from file name, by delegating to http.ServeContent. Error is returned
+// if file can not be opened or if it is directory, in which case nothing is
+// written to response, so error can be passed to SendError.
+func (c *Context) File(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	http.ServeContent(c.Response, c.requestWithContext(), info.Name(), info.ModTime(), f)
+	return nil
+}
+
+// streamRange writes part of content requested by Range header of request.
+func (c *Context) streamRange(r io.ReadSeeker) (int64, error) {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	size := end - start
+
+	offset, length, ok := parseRange(c.Request.Header.Get("Range"), size)
+	if !ok {
+		c.Response.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(c.Response, http.StatusText(http.StatusRequestedRangeNotSatisfiable), http.StatusRequestedRangeNotSatisfiable)
+		return 0, ErrRangeNotSatisfiable
+	}
+	if _, err := r.Seek(start+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	if length == size {
+		// multiple ranges, or range that covers whole content
+		c.Response.WriteHeader(http.StatusOK)
+		return c.stream(r)
+	}
+	header := c.Response.Header()
+	header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+	header.Set("Content-Length", strconv.FormatInt(length, 10))
+	c.Response.WriteHeader(http.StatusPartialContent)
+	return c.stream(io.LimitReader(r, length))
+}
+
+// parseRange parses value of Range header for content of provided size and
+// returns offset and length of requested part. If header contains multiple
+// ranges, whole content is returned. Boolean is false if header is malformed
+// or range is not satisfiable.
+func parseRange(header string, size int64) (offset, length int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found {
+		return 0, 0, false
+	}
+	if strings.Contains(spec, ",") {
+		return 0, size, true
+	}
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+	if first == "" {
+		// suffix range, last n bytes
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 || size == 0 {
+			return 0, 0, false
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, true
+	}
+	offset, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || offset < 0 || offset >= size {
+		return 0, 0, false
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < offset {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return offset, end - offset + 1, true
+}
+
+// stream copies data from r to response, flushing it periodically.
+func (c *Context) stream(r io.Reader) (int64, error) {
+	flusher, _ := c.Response.(http.Flusher)
+	buf := make([]byte, streamBufferSize)
+	lastFlush := time.Now()
+	var written int64
+	for {
+		select {
+		case <-c.Done():
+			return written, c.Err()
+		default:
+		}
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			w, err := c.Response.Write(buf[:n])
+			written += int64(w)
+			if err != nil {
+				return written, err
+			}
+			if flusher != nil && time.Since(lastFlush) >= StreamFlushInterval {
+				flusher.Flush()
+				lastFlush = time.Now()
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+	if flusher != nil {
+		flusher.Flush()
+	}
+	return written, nil
+}
+
+// StreamJSON responds with provided status and writes values received from
+// channel as elements of JSON array, flushing each of them to client as soon
+// as it is written. Array is closed when channel is closed. If context is
+// done before that, streaming stops without closing array, so client does not
+// mistake incomplete output for complete one, and context error is returned.
+// If value can not be encoded, streaming stops the same way, and error is
+// recorded with Error method and returned.
+func (c *Context) StreamJSON(status int, ch <-chan interface{}) error {
+	c.Response.Header().Set("Content-Type", "application/json; charset=utf-8")
+	c.Response.WriteHeader(status)
+	flusher, _ := c.Response.(http.Flusher)
+	write := func(b []byte) error {
+		if _, err := c.Response.Write(b); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	if err := write([]byte("[")); err != nil {
+		return err
+	}
+	first := true
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		case v, ok := <-ch:
+			if !ok {
+				return write([]byte("]\n"))
+			}
+			element, err := json.Marshal(v)
+			if err != nil {
+				c.Error(err)
+				return err
+			}
+			if !first {
+				element = append([]byte(","), element...)
+			}
+			first = false
+			if err := write(element); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// NDJSON responds with provided status and returns function that writes each
+// value passed to it as line of newline delimited JSON, flushing it to client
+// as soon as it is written. This is suitable for streaming logs or events.
+// Writing stops when context is done, in which case context error is
+// returned. If value can not be encoded or written, stream is stopped, error
+// is recorded with Error method and returned. After stream is stopped, every
+// following call returns the same error without writing anything.
+func (c *Context) NDJSON(status int) func(v interface{}) error {
+	c.Response.Header().Set("Content-Type", "application/x-ndjson")
+	c.Response.WriteHeader(status)
+	flusher, _ := c.Response.(http.Flusher)
+	var stopped error
+	return func(v interface{}) error {
+		if stopped != nil {
+			return stopped
+		}
+		if err := c.Err(); err != nil {
+			stopped = err
+			return err
+		}
+		line, err := json.Marshal(v)
+		if err == nil {
+			_, err = c.Response.Write(append(line, '\n'))
+		}
+		if err != nil {
+			stopped = err
+			c.Error(err)
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+}
+
+// StreamWithTimeout streams response in chunks written by step, calling it
+// repeatedly until it returns false. Each chunk is flushed to client, and
+// writing of each chunk is bounded by perChunk timeout through write
+// deadline of connection, so stalled client does not keep handler blocked
+// indefinitely. Stream is aborted with error if writing of chunk fails, for
+// example because timeout has passed, and error is recorded with Error
+// method. Streaming also stops when context is done, in which case context
+// error is returned. If underlying response writer does not support write
+// deadlines, error that wraps http.ErrNotSupported is returned before step is
+// called.
+func (c *Context) StreamWithTimeout(perChunk time.Duration, step func(io.Writer) bool) error {
+	rc := http.NewResponseController(c.Response)
+	w := &chunkWriter{w: c.Response}
+	defer rc.SetWriteDeadline(time.Time{})
+	for {
+		select {
+		case <-c.Done():
+			return c.Err()
+		default:
+		}
+		if err := rc.SetWriteDeadline(time.Now().Add(perChunk)); err != nil {
+			return err
+		}
+		more := step(w)
+		if w.err == nil {
+			w.err = rc.Flush()
+		}
+		if w.err != nil {
+			c.Error(w.err)
+			return w.err
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+// chunkWriter remembers first error of writes to underlying writer and
+// rejects all writes after it.
+type chunkWriter struct {
+	w   io.Writer
+	err error
+}
+
+// Write implements io.Writer interface.
+func (w *chunkWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(b)
+	w.err = err
+	return n, err
+}
@@ -0,0 +1,323 @@
+package mezvaro
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+func TestStreamReader(t *testing.T) {
+	data := bytes.Repeat([]byte("mezvaro"), 10000)
+	var written int64
+	var err error
+	m := New(HandlerFunc(func(c *Context) {
+		written, err = c.StreamReader(http.StatusOK, "application/octet-stream", bytes.NewReader(data))
+	}))
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, nil)
+	if err != nil {
+		t.Fatal("Streaming failed: ", err)
+	}
+	if written != int64(len(data)) {
+		t.Fatal("Wrong number of bytes written: ", written)
+	}
+	if !bytes.Equal(response.Body.Bytes(), data) {
+		t.Fatal("Streamed body does not match source.")
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "application/octet-stream" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if !response.Flushed {
+		t.Fatal("Response not flushed.")
+	}
+}
+
+func TestStreamReaderCancelled(t *testing.T) {
+	var written int64
+	var err error
+	m := New(HandlerFunc(func(c *Context) {
+		cancel := c.WithCancel()
+		cancel()
+		written, err = c.StreamReader(http.StatusOK, "", bytes.NewReader([]byte("data")))
+	}))
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if err == nil {
+		t.Fatal("Streaming not stopped for cancelled context.")
+	}
+	if written != 0 {
+		t.Fatal("Data written after cancellation.")
+	}
+}
+
+func TestStreamReaderRange(t *testing.T) {
+	data := []byte("0123456789")
+	tests := map[string]string{
+		"bytes=2-5":  "2345",
+		"bytes=7-":   "789",
+		"bytes=-3":   "789",
+		"bytes=8-20": "89",
+	}
+	for header, expected := range tests {
+		m := New(HandlerFunc(func(c *Context) {
+			c.StreamReader(http.StatusOK, "text/plain", bytes.NewReader(data))
+		}))
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Range", header)
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		if response.Code != http.StatusPartialContent {
+			t.Fatal("Expected 206 for ", header, ", got: ", response.Code)
+		}
+		if response.Body.String() != expected {
+			t.Fatal("Wrong slice for ", header, ": ", response.Body.String())
+		}
+	}
+}
+
+func TestStreamReaderRangeNotSatisfiable(t *testing.T) {
+	var err error
+	m := New(HandlerFunc(func(c *Context) {
+		_, err = c.StreamReader(http.StatusOK, "text/plain", bytes.NewReader([]byte("data")))
+	}))
+	for _, header := range []string{"bytes=10-", "bytes=3-1", "items=0-1", "bytes=abc"} {
+		request, _ := http.NewRequest("GET", "/", nil)
+		request.Header.Set("Range", header)
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		if response.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatal("Expected 416 for ", header, ", got: ", response.Code)
+		}
+		if err != ErrRangeNotSatisfiable {
+			t.Fatal("Expected range error, got: ", err)
+		}
+		if cr := response.Header().Get("Content-Range"); cr != "bytes */4" {
+			t.Fatal("Wrong content range: ", cr)
+		}
+	}
+}
+
+func TestFileRange(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "data.txt")
+	if err := os.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := New(HandlerFunc(func(c *Context) {
+		if err := c.File(name); err != nil {
+			c.SendError(err)
+		}
+	}))
+	request, _ := http.NewRequest("GET", "/", nil)
+	request.Header.Set("Range", "bytes=3-4")
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusPartialContent || response.Body.String() != "34" {
+		t.Fatal("Range not served from file: ", response.Code, response.Body.String())
+	}
+}
+
+func TestFileMissing(t *testing.T) {
+	m := New(HandlerFunc(func(c *Context) {
+		if err := c.File(filepath.Join(t.TempDir(), "missing")); err != nil {
+			c.SendError(err)
+		}
+	}))
+	request, _ := http.NewRequest("GET", "/", nil)
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	if response.Code != http.StatusNotFound {
+		t.Fatal("Expected 404 for missing file, got: ", response.Code)
+	}
+}
+
+func TestStreamJSON(t *testing.T) {
+	ch := make(chan interface{})
+	go func() {
+		defer close(ch)
+		ch <- map[string]int{"id": 1}
+		ch <- "two"
+		ch <- 3
+	}()
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	if err := c.StreamJSON(http.StatusOK, ch); err != nil {
+		t.Fatal("Streaming failed: ", err)
+	}
+	var values []interface{}
+	if err := json.Unmarshal(response.Body.Bytes(), &values); err != nil {
+		t.Fatal("Output is not valid JSON: ", response.Body.String())
+	}
+	if len(values) != 3 || values[1] != "two" {
+		t.Fatal("Wrong values streamed: ", values)
+	}
+	if !response.Flushed {
+		t.Fatal("Values not flushed.")
+	}
+}
+
+func TestStreamJSONEmpty(t *testing.T) {
+	ch := make(chan interface{})
+	close(ch)
+	response := httptest.NewRecorder()
+	NewContext(response, nil).StreamJSON(http.StatusOK, ch)
+	if response.Body.String() != "[]\n" {
+		t.Fatal("Wrong output for empty channel: ", response.Body.String())
+	}
+}
+
+func TestStreamJSONCancelled(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	cancel := c.WithTimeout(10 * time.Millisecond)
+	defer cancel()
+	if err := c.StreamJSON(http.StatusOK, make(chan interface{})); err == nil {
+		t.Fatal("Streaming not stopped on cancel.")
+	}
+	if response.Body.String() != "[" || json.Valid(response.Body.Bytes()) {
+		t.Fatal("Array closed on cancel: ", response.Body.String())
+	}
+}
+
+func TestStreamJSONEncodingError(t *testing.T) {
+	ch := make(chan interface{}, 2)
+	ch <- 1
+	ch <- make(chan int)
+	close(ch)
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	if err := c.StreamJSON(http.StatusOK, ch); err == nil {
+		t.Fatal("Encoding error not returned.")
+	}
+	if json.Valid(response.Body.Bytes()) {
+		t.Fatal("Incomplete output is valid JSON: ", response.Body.String())
+	}
+	if len(c.Errors()) != 1 {
+		t.Fatal("Encoding error not recorded.")
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	write := c.NDJSON(http.StatusOK)
+	for _, v := range []interface{}{map[string]int{"id": 1}, "two", 3} {
+		if err := write(v); err != nil {
+			t.Fatal("Writing failed: ", err)
+		}
+	}
+	if response.Body.String() != "{\"id\":1}\n\"two\"\n3\n" {
+		t.Fatal("Wrong output: ", response.Body.String())
+	}
+	if ct := response.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatal("Wrong content type: ", ct)
+	}
+	if !response.Flushed {
+		t.Fatal("Values not flushed.")
+	}
+}
+
+func TestNDJSONEncodingError(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	write := c.NDJSON(http.StatusOK)
+	write(1)
+	if err := write(make(chan int)); err == nil {
+		t.Fatal("Encoding error not returned.")
+	}
+	if err := write(2); err == nil {
+		t.Fatal("Stream not stopped after error.")
+	}
+	if response.Body.String() != "1\n" || len(c.Errors()) != 1 {
+		t.Fatal("Wrong output after error: ", response.Body.String(), c.Errors())
+	}
+}
+
+func TestNDJSONCancelled(t *testing.T) {
+	response := httptest.NewRecorder()
+	c := NewContext(response, nil)
+	cancel := c.WithCancel()
+	write := c.NDJSON(http.StatusOK)
+	cancel()
+	if err := write(1); err != context.Canceled {
+		t.Fatal("Stream not stopped on cancel: ", err)
+	}
+	if response.Body.Len() != 0 {
+		t.Fatal("Data written after cancel: ", response.Body.String())
+	}
+}
+
+func TestStreamWithTimeout(t *testing.T) {
+	server := httptest.NewServer(New(HandlerFunc(func(c *Context) {
+		chunks := 0
+		err := c.StreamWithTimeout(time.Second, func(w io.Writer) bool {
+			chunks++
+			io.WriteString(w, "chunk\n")
+			return chunks < 3
+		})
+		if err != nil {
+			t.Error("Streaming failed: ", err)
+		}
+	})))
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	if string(body) != "chunk\nchunk\nchunk\n" {
+		t.Fatal("Wrong body: ", string(body))
+	}
+}
+
+func TestStreamWithTimeoutSlowClient(t *testing.T) {
+	result := make(chan error, 1)
+	server := httptest.NewServer(New(HandlerFunc(func(c *Context) {
+		chunk := bytes.Repeat([]byte("x"), 1<<20)
+		steps := 0
+		result <- c.StreamWithTimeout(50*time.Millisecond, func(w io.Writer) bool {
+			steps++
+			w.Write(chunk)
+			// client never reads, so socket buffers are filled long
+			// before this limit
+			return steps < 1000
+		})
+	})))
+	defer server.Close()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	select {
+	case err := <-result:
+		var netErr net.Error
+		if !errors.As(err, &netErr) || !netErr.Timeout() {
+			t.Fatal("Expected timeout error, got: ", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Stream to stalled client was not aborted.")
+	}
+}
+
+func TestStreamWithTimeoutNotSupported(t *testing.T) {
+	c := NewContext(httptest.NewRecorder(), nil)
+	called := false
+	err := c.StreamWithTimeout(time.Second, func(w io.Writer) bool {
+		called = true
+		return false
+	})
+	if !errors.Is(err, http.ErrNotSupported) || called {
+		t.Fatal("Expected not supported error, got: ", err)
+	}
+}
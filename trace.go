@@ -0,0 +1,75 @@
+package mezvaro
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// TraceEvent is type of event recorded in execution trace.
+type TraceEvent int
+
+const (
+	// TraceEnter is recorded when handler is invoked.
+	TraceEnter TraceEvent = iota
+	// TraceExit is recorded when handler returns.
+	TraceExit
+)
+
+// String implements fmt.Stringer interface.
+func (e TraceEvent) String() string {
+	if e == TraceEnter {
+		return "enter"
+	}
+	return "exit"
+}
+
+// TraceEntry describes single event in execution of handler chain.
+type TraceEntry struct {
+	// Name of handler. For HandlerFunc it is name of function, for other
+	// handlers name of the type.
+	Name string
+	// Event that occurred.
+	Event TraceEvent
+	// Aborted indicates if chain was aborted at the moment of event.
+	Aborted bool
+}
+
+// Trace returns sequence of handlers entered and exited during execution of
+// chain. Handlers are traced only if Mezvaro is in debug mode (see WithDebug),
+// otherwise nil is returned.
+func (c *Context) Trace() []TraceEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.trace == nil {
+		return nil
+	}
+	trace := make([]TraceEntry, len(c.trace))
+	copy(trace, c.trace)
+	return trace
+}
+
+// traceHandle invokes handler recording its entry and exit.
+func (c *Context) traceHandle(h Handler) {
+	name := handlerName(h)
+	c.addTraceEntry(name, TraceEnter)
+	h.Handle(c)
+	c.addTraceEntry(name, TraceExit)
+}
+
+// addTraceEntry records event in execution trace.
+func (c *Context) addTraceEntry(name string, event TraceEvent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trace = append(c.trace, TraceEntry{Name: name, Event: event, Aborted: c.IsAborted()})
+}
+
+// handlerName returns human readable name of handler.
+func handlerName(h Handler) string {
+	if hf, ok := h.(HandlerFunc); ok {
+		if f := runtime.FuncForPC(reflect.ValueOf(hf).Pointer()); f != nil {
+			return f.Name()
+		}
+	}
+	return fmt.Sprintf("%T", h)
+}
@@ -0,0 +1,79 @@
+package mezvaro
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type namedHandler struct{}
+
+func (namedHandler) Handle(c *Context) {}
+
+func traceFirst(c *Context) {
+	c.Next()
+}
+
+func traceAbort(c *Context) {
+	c.Abort()
+}
+
+func traceNeverCalled(c *Context) {}
+
+func TestTraceAbort(t *testing.T) {
+	var trace []TraceEntry
+	m := New().With(WithDebug(true))
+	m.UseFunc(func(c *Context) {
+		c.Next()
+		trace = c.Trace()
+	})
+	m.UseFunc(traceFirst, traceAbort, traceNeverCalled)
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+
+	expected := []struct {
+		name    string
+		event   TraceEvent
+		aborted bool
+	}{
+		{"traceFirst", TraceEnter, false},
+		{"traceAbort", TraceEnter, false},
+		{"traceAbort", TraceExit, true},
+		{"traceFirst", TraceExit, true},
+	}
+	// first entry is handler that collects trace, it did not exit yet
+	trace = trace[1:]
+	if len(trace) != len(expected) {
+		t.Fatal("Wrong trace length: ", trace)
+	}
+	for i, e := range expected {
+		if !strings.HasSuffix(trace[i].Name, "."+e.name) {
+			t.Fatalf("Expected handler %s at position %d, got: %s", e.name, i, trace[i].Name)
+		}
+		if trace[i].Event != e.event || trace[i].Aborted != e.aborted {
+			t.Fatalf("Wrong event at position %d: %+v", i, trace[i])
+		}
+	}
+}
+
+func TestTraceHandlerName(t *testing.T) {
+	var trace []TraceEntry
+	m := New(namedHandler{}).With(WithDebug(true))
+	m.Fork().UseFunc(func(c *Context) {
+		trace = c.Trace()
+	}).ServeHTTP(httptest.NewRecorder(), nil)
+	if len(trace) < 2 || trace[0].Name != "mezvaro.namedHandler" {
+		t.Fatal("Wrong name of handler: ", trace)
+	}
+}
+
+func TestTraceDisabled(t *testing.T) {
+	var trace []TraceEntry
+	m := New(HandlerFunc(traceFirst))
+	m.UseFunc(func(c *Context) {
+		trace = c.Trace()
+	})
+	m.ServeHTTP(httptest.NewRecorder(), nil)
+	if trace != nil {
+		t.Fatal("Trace recorded without debug mode.")
+	}
+}
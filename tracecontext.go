@@ -0,0 +1,50 @@
+package mezvaro
+
+import "strings"
+
+// TraceContext returns trace ID and span (parent) ID from W3C traceparent
+// header of request, as lower case hex strings. Boolean is false if header is
+// missing or malformed. Only parsing is done, so IDs can be used for
+// correlating logs without full tracing integration.
+func (c *Context) TraceContext() (traceID, spanID string, ok bool) {
+	if c.Request == nil {
+		return "", "", false
+	}
+	return parseTraceparent(c.Request.Header.Get("traceparent"))
+}
+
+// parseTraceparent parses value of traceparent header, in format
+// "version-traceid-parentid-flags".
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	header = strings.TrimSpace(header)
+	// version 00 has exact length, future versions can append fields
+	if len(header) < 55 || len(header) > 55 && header[55] != '-' || header[:2] == "00" && len(header) != 55 {
+		return "", "", false
+	}
+	if header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return "", "", false
+	}
+	version, traceID, spanID, flags := header[:2], header[3:35], header[36:52], header[53:55]
+	if !isLowerHex(version) || version == "ff" || !isLowerHex(flags) {
+		return "", "", false
+	}
+	if !isLowerHex(traceID) || isZeros(traceID) || !isLowerHex(spanID) || isZeros(spanID) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// isLowerHex checks if string consists only of lower case hex digits.
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !(s[i] >= '0' && s[i] <= '9' || s[i] >= 'a' && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeros checks if string consists only of zeros.
+func isZeros(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
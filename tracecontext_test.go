@@ -0,0 +1,46 @@
+package mezvaro
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func traceContext(header string) (string, string, bool) {
+	request, _ := http.NewRequest("GET", "/", nil)
+	if header != "" {
+		request.Header.Set("traceparent", header)
+	}
+	c := newContext(httptest.NewRecorder(), request, nil, nil)
+	return c.TraceContext()
+}
+
+func TestTraceContext(t *testing.T) {
+	traceID, spanID, ok := traceContext("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("Valid traceparent rejected.")
+	}
+	if traceID != "4bf92f3577b34da6a3ce929d0e0e4736" || spanID != "00f067aa0ba902b7" {
+		t.Fatal("Wrong IDs: ", traceID, spanID)
+	}
+	if _, _, ok := traceContext("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future"); !ok {
+		t.Fatal("Traceparent of future version rejected.")
+	}
+}
+
+func TestTraceContextMalformed(t *testing.T) {
+	for _, header := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01",
+	} {
+		if _, _, ok := traceContext(header); ok {
+			t.Fatal("Malformed traceparent accepted: ", header)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package mezvaro
+
+// MaxTransformSize is maximal size of response body, in bytes, buffered by
+// TransformResponse middleware.
+var MaxTransformSize = 4 << 20
+
+// TransformResponse returns middleware that buffers response body and
+// applies fn to it before it is written, for example for minification of HTML
+// or rewriting of links. Status and headers of response are preserved, and
+// Content-Length header is set to length of transformed body. Only bodies
+// with textual content type (text, JSON, XML and JavaScript) that are not
+// encoded are transformed. Responses larger than MaxTransformSize, and
+// responses flushed by handlers, are streamed without transformation. If
+// rest of chain panics, buffered response is discarded, so recovery
+// middleware before TransformResponse can respond with error instead.
+func TransformResponse(fn func([]byte) []byte) Handler {
+	return HandlerFunc(func(c *Context) {
+		c.bufferResponse(MaxTransformSize, fn)
+	})
+}
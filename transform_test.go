@@ -0,0 +1,77 @@
+package mezvaro
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func serveTransformed(contentType string, body []byte) *httptest.ResponseRecorder {
+	m := New(TransformResponse(bytes.ToUpper))
+	m.UseFunc(func(c *Context) {
+		if contentType != "" {
+			c.Response.Header().Set("Content-Type", contentType)
+		}
+		c.Response.WriteHeader(http.StatusAccepted)
+		c.Response.Write(body)
+	})
+	request, _ := http.NewRequest("GET", "/", nil)
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response
+}
+
+func TestTransformResponse(t *testing.T) {
+	response := serveTransformed("", []byte("<html>hello</html>"))
+	if response.Code != http.StatusAccepted {
+		t.Fatal("Status not preserved: ", response.Code)
+	}
+	if response.Body.String() != "<HTML>HELLO</HTML>" {
+		t.Fatal("Body not transformed: ", response.Body.String())
+	}
+	if ct := response.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatal("Content type not detected from original body: ", ct)
+	}
+	if cl := response.Header().Get("Content-Length"); cl != strconv.Itoa(response.Body.Len()) {
+		t.Fatal("Wrong content length: ", cl)
+	}
+}
+
+func TestTransformResponseBinary(t *testing.T) {
+	response := serveTransformed("image/png", []byte("png"))
+	if response.Body.String() != "png" {
+		t.Fatal("Binary body transformed: ", response.Body.String())
+	}
+}
+
+func TestTransformResponseOversized(t *testing.T) {
+	previous := MaxTransformSize
+	MaxTransformSize = 10
+	defer func() { MaxTransformSize = previous }()
+	response := serveTransformed("text/plain", []byte("larger than limit"))
+	if response.Code != http.StatusAccepted || response.Body.String() != "larger than limit" {
+		t.Fatal("Oversized body not streamed as is: ", response.Code, response.Body.String())
+	}
+}
+
+func TestTransformResponsePanic(t *testing.T) {
+	m := New(Recovery(slog.New(slog.NewTextHandler(io.Discard, nil))), TransformResponse(bytes.ToUpper))
+	m.UseFunc(func(c *Context) {
+		c.Response.Header().Set("Content-Type", "text/plain")
+		c.Response.Write([]byte("partial"))
+		panic("broken")
+	})
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, httptest.NewRequest("GET", "/", nil))
+	if response.Code != http.StatusInternalServerError {
+		t.Fatal("Wrong status after panic: ", response.Code)
+	}
+	if strings.Contains(strings.ToLower(response.Body.String()), "partial") {
+		t.Fatal("Partial response written after panic: ", response.Body.String())
+	}
+}
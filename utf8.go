@@ -0,0 +1,113 @@
+package mezvaro
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidUTF8 is error of requests rejected by ValidUTF8 middleware.
+var ErrInvalidUTF8 = errors.New("mezvaro: request contains invalid UTF-8")
+
+// ValidUTF8 returns middleware that checks that query parameters and body of
+// request are valid UTF-8, so handlers and services they call are not
+// confused by malformed input. Only bodies with textual content type (text,
+// JSON, XML and URL encoded forms) in UTF-8 charset are checked, so binary
+// uploads and bodies transcoded with WithCharsetTranscoding pass through.
+// Body is read with Body method, so limits on its size are respected.
+//
+// Invalid requests are rejected with 400 Bad Request status and chain is
+// aborted. If sanitize is true, invalid sequences are replaced with Unicode
+// replacement character (U+FFFD) instead, and request is passed on.
+func ValidUTF8(sanitize bool) Handler {
+	return HandlerFunc(func(c *Context) {
+		if !validQuery(c.Request, sanitize) {
+			c.AbortWithError(http.StatusBadRequest, ErrInvalidUTF8)
+			return
+		}
+		if textualBody(c) {
+			body, err := c.Body()
+			if err != nil {
+				c.SendError(err)
+				return
+			}
+			if !utf8.Valid(body) {
+				if !sanitize {
+					c.AbortWithError(http.StatusBadRequest, ErrInvalidUTF8)
+					return
+				}
+				c.replaceBody(bytes.ToValidUTF8(body, []byte(string(utf8.RuneError))))
+			}
+		}
+		c.Next()
+	})
+}
+
+// validQuery checks if query parameters of request are valid UTF-8. If
+// sanitize is true, invalid parameters are replaced with sanitized ones and
+// true is returned.
+func validQuery(r *http.Request, sanitize bool) bool {
+	if r == nil || r.URL == nil || r.URL.RawQuery == "" {
+		return true
+	}
+	query := r.URL.Query()
+	sanitized := make(url.Values, len(query))
+	valid := true
+	for key, values := range query {
+		if !utf8.ValidString(key) {
+			valid = false
+			key = strings.ToValidUTF8(key, string(utf8.RuneError))
+		}
+		for _, value := range values {
+			if !utf8.ValidString(value) {
+				valid = false
+				value = strings.ToValidUTF8(value, string(utf8.RuneError))
+			}
+			sanitized[key] = append(sanitized[key], value)
+		}
+	}
+	if valid {
+		return true
+	}
+	if !sanitize {
+		return false
+	}
+	r.URL.RawQuery = sanitized.Encode()
+	return true
+}
+
+// textualBody checks if request has body with textual content type in UTF-8
+// charset.
+func textualBody(c *Context) bool {
+	if c.Request == nil || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return false
+	}
+	if charset := c.Charset(); charset != "" && charset != "utf-8" && charset != "utf8" {
+		return false
+	}
+	return textualMediaType(c.ContentType())
+}
+
+// textualMediaType checks if media type is text, JSON, XML, JavaScript or URL
+// encoded form.
+func textualMediaType(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml") ||
+		mediaType == "application/javascript" ||
+		mediaType == "application/x-www-form-urlencoded"
+}
+
+// replaceBody replaces body of request, and its cached copy, with provided
+// body.
+func (c *Context) replaceBody(body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.body = body
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	c.Request.ContentLength = int64(len(body))
+}
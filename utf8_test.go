@@ -0,0 +1,71 @@
+package mezvaro
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveValidUTF8(sanitize bool, target, contentType, body string) (*httptest.ResponseRecorder, *http.Request, string) {
+	var received *http.Request
+	var receivedBody string
+	m := New(ValidUTF8(sanitize))
+	m.UseFunc(func(c *Context) {
+		received = c.Request
+		read, _ := io.ReadAll(c.Request.Body)
+		receivedBody = string(read)
+	})
+	request, _ := http.NewRequest("POST", target, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentType)
+	response := httptest.NewRecorder()
+	m.ServeHTTP(response, request)
+	return response, received, receivedBody
+}
+
+func TestValidUTF8(t *testing.T) {
+	response, received, body := serveValidUTF8(false, "/?name=%C5%BEaba", "application/json", `{"name":"žaba"}`)
+	if response.Code != http.StatusOK || received == nil {
+		t.Fatal("Valid request rejected: ", response.Code)
+	}
+	if received.URL.Query().Get("name") != "žaba" || body != `{"name":"žaba"}` {
+		t.Fatal("Valid request changed: ", received.URL.RawQuery, body)
+	}
+}
+
+func TestValidUTF8Rejected(t *testing.T) {
+	for _, tc := range []struct {
+		target string
+		body   string
+	}{
+		{"/?name=%FFaba", `{}`},
+		{"/?%FF=aba", `{}`},
+		{"/", "{\"name\":\"\xffaba\"}"},
+	} {
+		response, received, _ := serveValidUTF8(false, tc.target, "application/json", tc.body)
+		if response.Code != http.StatusBadRequest || received != nil {
+			t.Fatal("Invalid request not rejected: ", tc.target, response.Code)
+		}
+	}
+}
+
+func TestValidUTF8BinaryBody(t *testing.T) {
+	response, _, body := serveValidUTF8(false, "/", "image/png", "\x89PNG\xff")
+	if response.Code != http.StatusOK || body != "\x89PNG\xff" {
+		t.Fatal("Binary body checked: ", response.Code)
+	}
+}
+
+func TestValidUTF8Sanitize(t *testing.T) {
+	response, received, body := serveValidUTF8(true, "/?name=%FFaba", "text/plain; charset=utf-8", "\xffaba")
+	if response.Code != http.StatusOK || received == nil {
+		t.Fatal("Sanitized request rejected: ", response.Code)
+	}
+	if received.URL.Query().Get("name") != "�aba" {
+		t.Fatal("Query not sanitized: ", received.URL.Query().Get("name"))
+	}
+	if body != "�aba" || received.ContentLength != int64(len(body)) {
+		t.Fatal("Body not sanitized: ", body)
+	}
+}
@@ -0,0 +1,149 @@
+package mezvaro
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Signature schemes supported by WebhookVerify.
+const (
+	// WebhookSchemeHex expects header to contain hex encoded HMAC-SHA256
+	// of request body.
+	WebhookSchemeHex = "hex"
+	// WebhookSchemeGitHub expects header in format used by GitHub,
+	// "sha256=<hex encoded HMAC-SHA256 of body>".
+	WebhookSchemeGitHub = "github"
+	// WebhookSchemeStripe expects header in format used by Stripe,
+	// "t=<timestamp>,v1=<signature>", where signature is hex encoded
+	// HMAC-SHA256 of "<timestamp>.<body>". Multiple v1 signatures are
+	// allowed. Timestamp has to be within WebhookTolerance from current
+	// time.
+	WebhookSchemeStripe = "stripe"
+)
+
+// WebhookTolerance is maximal difference between timestamp of webhook
+// signature and current time, for signature schemes that include timestamp.
+// Requests with older or future timestamps are rejected, so captured requests
+// can not be replayed later. Zero disables the check.
+var WebhookTolerance = 5 * time.Minute
+
+// MaxWebhookSize is maximal size of webhook request body, in bytes, read by
+// WebhookVerify. Body is read before signature is verified, so limit protects
+// from unauthenticated clients sending huge bodies.
+var MaxWebhookSize int64 = 1 << 20
+
+// WebhookVerify creates middleware that verifies HMAC signature of webhook
+// request body, provided in request header with given name, using provided
+// secret and signature scheme. Requests with missing or invalid signature
+// are rejected with 401 Unauthorized status and chain is aborted. Requests
+// with body larger than MaxWebhookSize are rejected with 413 Request Entity
+// Too Large status. Body remains readable by following handlers. Panics if
+// scheme is not supported.
+func WebhookVerify(secret []byte, header string, scheme string) Handler {
+	var verify func(signature string, body []byte) bool
+	switch scheme {
+	case WebhookSchemeHex:
+		verify = func(signature string, body []byte) bool {
+			return validHMAC(secret, body, signature)
+		}
+	case WebhookSchemeGitHub:
+		verify = func(signature string, body []byte) bool {
+			return strings.HasPrefix(signature, "sha256=") &&
+				validHMAC(secret, body, strings.TrimPrefix(signature, "sha256="))
+		}
+	case WebhookSchemeStripe:
+		verify = func(signature string, body []byte) bool {
+			var timestamp string
+			var signatures []string
+			for _, part := range strings.Split(signature, ",") {
+				kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				switch kv[0] {
+				case "t":
+					timestamp = kv[1]
+				case "v1":
+					signatures = append(signatures, kv[1])
+				}
+			}
+			if timestamp == "" || !withinTolerance(timestamp) {
+				return false
+			}
+			payload := append([]byte(timestamp+"."), body...)
+			for _, s := range signatures {
+				if validHMAC(secret, payload, s) {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		panic("mezvaro: unsupported webhook signature scheme " + scheme)
+	}
+
+	return HandlerFunc(func(c *Context) {
+		signature := c.Request.Header.Get(header)
+		if signature == "" {
+			http.Error(c.Response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		if c.Request.ContentLength > MaxWebhookSize {
+			c.SendError(&http.MaxBytesError{Limit: MaxWebhookSize})
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, MaxWebhookSize)
+		}
+		body, err := c.Body()
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.SendError(err)
+			return
+		}
+		if err != nil {
+			http.Error(c.Response, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			c.Abort()
+			return
+		}
+		if !verify(signature, body) {
+			http.Error(c.Response, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			c.Abort()
+			return
+		}
+		c.Next()
+	})
+}
+
+// withinTolerance checks if Unix timestamp is within WebhookTolerance from
+// current time.
+func withinTolerance(timestamp string) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if WebhookTolerance <= 0 {
+		return true
+	}
+	diff := time.Since(time.Unix(seconds, 0))
+	return diff <= WebhookTolerance && diff >= -WebhookTolerance
+}
+
+// validHMAC checks in constant time if hex encoded signature is HMAC-SHA256
+// of payload.
+func validHMAC(secret, payload []byte, signature string) bool {
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,133 @@
+package mezvaro
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var webhookSecret = []byte("webhook secret")
+
+const webhookPayload = `{"event": "push"}`
+
+func sign(payload string) string {
+	mac := hmac.New(sha256.New, webhookSecret)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// stripeSignature returns valid Stripe signature of payload with provided
+// timestamp.
+func stripeSignature(timestamp time.Time) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + sign(t+"."+webhookPayload)
+}
+
+func webhookRequest(scheme, header, signature string) (*httptest.ResponseRecorder, string) {
+	var body string
+	m := New(WebhookVerify(webhookSecret, header, scheme))
+	m.UseFunc(func(c *Context) {
+		b, _ := io.ReadAll(c.Request.Body)
+		body = string(b)
+	})
+	response := httptest.NewRecorder()
+	request, _ := http.NewRequest("POST", "/", strings.NewReader(webhookPayload))
+	request.Header.Set(header, signature)
+	m.ServeHTTP(response, request)
+	return response, body
+}
+
+func TestWebhookVerifyValid(t *testing.T) {
+	cases := []struct {
+		scheme    string
+		header    string
+		signature string
+	}{
+		{WebhookSchemeHex, "X-Signature", sign(webhookPayload)},
+		{WebhookSchemeGitHub, "X-Hub-Signature-256", "sha256=" + sign(webhookPayload)},
+		{WebhookSchemeStripe, "Stripe-Signature", stripeSignature(time.Now())},
+	}
+	for _, tc := range cases {
+		response, body := webhookRequest(tc.scheme, tc.header, tc.signature)
+		if response.Code != http.StatusOK {
+			t.Fatalf("Valid %s signature rejected with status %d.", tc.scheme, response.Code)
+		}
+		if body != webhookPayload {
+			t.Fatalf("Body not readable by handler for %s scheme: %s", tc.scheme, body)
+		}
+	}
+}
+
+func TestWebhookVerifyInvalid(t *testing.T) {
+	cases := []struct {
+		scheme    string
+		header    string
+		signature string
+	}{
+		{WebhookSchemeHex, "X-Signature", sign("tampered")},
+		{WebhookSchemeHex, "X-Signature", "not hex"},
+		{WebhookSchemeHex, "X-Signature", ""},
+		{WebhookSchemeGitHub, "X-Hub-Signature-256", sign(webhookPayload)},
+		{WebhookSchemeStripe, "Stripe-Signature", "t=1492774577,v1=" + sign(webhookPayload)},
+		{WebhookSchemeStripe, "Stripe-Signature", "v1=" + sign("."+webhookPayload)},
+		// replayed and future signatures
+		{WebhookSchemeStripe, "Stripe-Signature", stripeSignature(time.Now().Add(-10 * time.Minute))},
+		{WebhookSchemeStripe, "Stripe-Signature", stripeSignature(time.Now().Add(10 * time.Minute))},
+		{WebhookSchemeStripe, "Stripe-Signature", "t=now,v1=" + sign("now."+webhookPayload)},
+	}
+	for _, tc := range cases {
+		response, body := webhookRequest(tc.scheme, tc.header, tc.signature)
+		if response.Code != http.StatusUnauthorized {
+			t.Fatalf("Invalid %s signature %q accepted with status %d.", tc.scheme, tc.signature, response.Code)
+		}
+		if body != "" {
+			t.Fatal("Handler called for invalid signature.")
+		}
+	}
+}
+
+func TestWebhookToleranceDisabled(t *testing.T) {
+	previous := WebhookTolerance
+	WebhookTolerance = 0
+	defer func() { WebhookTolerance = previous }()
+	response, _ := webhookRequest(WebhookSchemeStripe, "Stripe-Signature", stripeSignature(time.Unix(1492774577, 0)))
+	if response.Code != http.StatusOK {
+		t.Fatal("Old signature rejected with disabled tolerance: ", response.Code)
+	}
+}
+
+func TestWebhookVerifyUnsupportedScheme(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Unsupported scheme accepted.")
+		}
+	}()
+	WebhookVerify(webhookSecret, "X-Signature", "md5")
+}
+
+func TestWebhookVerifyBodyLimit(t *testing.T) {
+	previous := MaxWebhookSize
+	MaxWebhookSize = 8
+	defer func() { MaxWebhookSize = previous }()
+	for _, contentLength := range []int64{-1, int64(len(webhookPayload))} {
+		var called bool
+		m := New(WebhookVerify(webhookSecret, "X-Signature", WebhookSchemeHex))
+		m.UseFunc(func(c *Context) { called = true })
+		// reader without length, so size is known only while reading
+		request, _ := http.NewRequest("POST", "/", io.MultiReader(strings.NewReader(webhookPayload)))
+		request.ContentLength = contentLength
+		request.Header.Set("X-Signature", sign(webhookPayload))
+		response := httptest.NewRecorder()
+		m.ServeHTTP(response, request)
+		if response.Code != http.StatusRequestEntityTooLarge || called {
+			t.Fatal("Oversized body accepted: ", response.Code)
+		}
+	}
+}